@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -87,19 +88,32 @@ func fetchWithAuth(method string, url string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkStatus(method, url, code, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// checkStatus turns a response that isn't a 200 into an error
+func checkStatus(method string, url string, code int, body []byte) error {
 	if code == 402 {
-		return nil, fmt.Errorf("To run and submit the tests for this lesson, you must have an active Boot.dev membership\nhttps://boot.dev/pricing")
+		return fmt.Errorf("To run and submit the tests for this lesson, you must have an active Boot.dev membership\nhttps://boot.dev/pricing")
 	}
 	if code != 200 {
-		return nil, fmt.Errorf("failed to %s to %s\nResponse: %d %s", method, url, code, string(body))
+		return fmt.Errorf("failed to %s to %s\nResponse: %d %s", method, url, code, string(body))
 	}
-	return body, err
+	return nil
 }
 
 func fetchWithAuthAndPayload(method string, url string, payload []byte) ([]byte, int, error) {
+	return fetchWithAuthContext(context.Background(), method, url, payload)
+}
+
+// fetchWithAuthContext makes an authenticated request that stops when ctx is canceled
+func fetchWithAuthContext(ctx context.Context, method string, url string, payload []byte) ([]byte, int, error) {
 	api_url := viper.GetString("api_url")
 	client := &http.Client{}
-	r, err := http.NewRequest(method, api_url+url, bytes.NewBuffer(payload))
+	r, err := http.NewRequestWithContext(ctx, method, api_url+url, bytes.NewBuffer(payload))
 	if err != nil {
 		return nil, 0, err
 	}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultChunkTokens is the estimated size of the lesson content sent in a
+	// single prompt, leaving room in the context window for the instructions,
+	// the existing questions and the answer
+	DefaultChunkTokens = 50000
+	// DefaultConcurrency is how many chunks are generated at the same time
+	DefaultConcurrency = 3
+)
+
+// contentChunk is a group of lessons of the same chapter generated in one prompt
+type contentChunk struct {
+	chapter   string
+	lessons   []*CourseLesson
+	tokens    int
+	questions int
+}
+
+// estimateTokens approximates the number of tokens of a text, about 4 characters each
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func chunkTokens() int {
+	if tokens := viper.GetInt("generate.chunk_tokens"); tokens > 0 {
+		return tokens
+	}
+	return DefaultChunkTokens
+}
+
+func generateConcurrency() int {
+	if concurrency := viper.GetInt("generate.concurrency"); concurrency > 0 {
+		return concurrency
+	}
+	return DefaultConcurrency
+}
+
+// chunkCourse splits the lessons with content into one chunk per chapter, or
+// several when a chapter is over the token budget. A single lesson over the
+// budget gets a chunk of its own and is cut when the prompt is built.
+func chunkCourse(c *Course, budget int) []contentChunk {
+	var chunks []contentChunk
+	for i := range c.Chapters {
+		chapter := &c.Chapters[i]
+		current := contentChunk{chapter: chapter.Title}
+		for j := range chapter.Lessons {
+			lesson := &chapter.Lessons[j]
+			if lesson.Content == "" {
+				continue
+			}
+			tokens := estimateTokens(lesson.Content)
+			if len(current.lessons) > 0 && current.tokens+tokens > budget {
+				chunks = append(chunks, current)
+				current = contentChunk{chapter: chapter.Title}
+			}
+			current.lessons = append(current.lessons, lesson)
+			current.tokens += tokens
+		}
+		if len(current.lessons) > 0 {
+			chunks = append(chunks, current)
+		}
+	}
+	return chunks
+}
+
+// allocateQuestions spreads the questions over the chunks in proportion to
+// their content, so late chapters are asked about as much as early ones. Every
+// chunk gets at least one question when there are enough of them, and chunks
+// are weighed evenly when none has content.
+func allocateQuestions(chunks []contentChunk, total int) {
+	if len(chunks) == 0 {
+		return
+	}
+	base := 0
+	if total >= len(chunks) {
+		base = 1
+	}
+	remaining := total - base*len(chunks)
+
+	tokens := 0
+	for _, chunk := range chunks {
+		tokens += chunk.tokens
+	}
+
+	// Largest remainder: round every share down, then hand out what is left
+	// to the chunks that lost the most in the rounding
+	remainders := make([]float64, len(chunks))
+	order := make([]int, len(chunks))
+	assigned := 0
+	for i := range chunks {
+		share := float64(remaining) / float64(len(chunks))
+		if tokens > 0 {
+			share = float64(remaining) * float64(chunks[i].tokens) / float64(tokens)
+		}
+		chunks[i].questions = base + int(share)
+		assigned += int(share)
+		remainders[i] = share - float64(int(share))
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case remainders[a] > remainders[b]:
+			return -1
+		case remainders[a] < remainders[b]:
+			return 1
+		}
+		return 0
+	})
+	for _, i := range order[:remaining-assigned] {
+		chunks[i].questions++
+	}
+}
+
+// promptLessons returns the numbered lessons of the chunk, cut to the token budget
+func (chunk *contentChunk) promptLessons(budget int) []PromptLesson {
+	lessons := make([]PromptLesson, len(chunk.lessons))
+	left := budget * 4
+	for i, l := range chunk.lessons {
+		content := l.Content
+		if len(content) > left {
+			content = strings.ToValidUTF8(content[:left], "")
+		}
+		left -= len(content)
+		lessons[i] = PromptLesson{Number: i + 1, Title: l.Title, Content: content}
+	}
+	return lessons
+}
+
+// lessonRefs identifies the lessons of the chunk, in the order they are numbered
+func (chunk *contentChunk) lessonRefs(c *Course) []dao.LessonRef {
+	refs := make([]dao.LessonRef, len(chunk.lessons))
+	for i, l := range chunk.lessons {
+		refs[i] = dao.LessonRef{LessonUUID: l.UUID, LessonSlug: l.Slug}
+		if chapter := c.GetChapter(l); chapter != nil {
+			refs[i].ChapterUUID = chapter.UUID
+			refs[i].ChapterSlug = chapter.Slug
+		}
+	}
+	return refs
+}
+
+// existingQuestions keeps the questions generated from the lessons of the
+// chunk, and the ones whose lesson is not known
+func (chunk *contentChunk) existingQuestions(existing []dao.Question) []dao.Question {
+	var questions []dao.Question
+	for _, q := range existing {
+		if q.LessonUUID == "" || slices.ContainsFunc(chunk.lessons, func(l *CourseLesson) bool { return l.UUID == q.LessonUUID }) {
+			questions = append(questions, q)
+		}
+	}
+	return questions
+}
+
+func (chunk *contentChunk) String() string {
+	if len(chunk.lessons) == 1 {
+		return fmt.Sprintf("%s (%s)", chunk.chapter, chunk.lessons[0].Title)
+	}
+	return fmt.Sprintf("%s (%d lessons)", chunk.chapter, len(chunk.lessons))
+}
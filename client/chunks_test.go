@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lesson returns a lesson whose content is about the given number of tokens
+func lesson(title string, tokens int) CourseLesson {
+	return CourseLesson{UUID: title, Title: title, Content: strings.Repeat("abcd", tokens)}
+}
+
+func TestChunkCourse(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters []CourseChapter
+		budget   int
+		// want is the chapter and lessons of each chunk
+		want []string
+	}{
+		{
+			name: "one chunk per chapter",
+			chapters: []CourseChapter{
+				{Title: "A", Lessons: []CourseLesson{lesson("a1", 10), lesson("a2", 10)}},
+				{Title: "B", Lessons: []CourseLesson{lesson("b1", 10)}},
+			},
+			budget: 100,
+			want:   []string{"A: a1 a2 (20)", "B: b1 (10)"},
+		},
+		{
+			name: "chapter split across chunks",
+			chapters: []CourseChapter{
+				{Title: "A", Lessons: []CourseLesson{lesson("a1", 40), lesson("a2", 40), lesson("a3", 40), lesson("a4", 10)}},
+			},
+			budget: 100,
+			want:   []string{"A: a1 a2 (80)", "A: a3 a4 (50)"},
+		},
+		{
+			name: "lesson larger than the budget",
+			chapters: []CourseChapter{
+				{Title: "A", Lessons: []CourseLesson{lesson("a1", 10), lesson("a2", 300), lesson("a3", 10)}},
+			},
+			budget: 100,
+			want:   []string{"A: a1 (10)", "A: a2 (300)", "A: a3 (10)"},
+		},
+		{
+			name: "lessons and chapters without content",
+			chapters: []CourseChapter{
+				{Title: "A", Lessons: []CourseLesson{{Title: "empty"}}},
+				{Title: "B", Lessons: []CourseLesson{lesson("b1", 10), {Title: "empty"}, lesson("b2", 10)}},
+				{Title: "C"},
+			},
+			budget: 100,
+			want:   []string{"B: b1 b2 (20)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, chunk := range chunkCourse(&Course{Chapters: tt.chapters}, tt.budget) {
+				titles := []string{}
+				for _, l := range chunk.lessons {
+					titles = append(titles, l.Title)
+				}
+				got = append(got, fmt.Sprintf("%s: %s (%d)", chunk.chapter, strings.Join(titles, " "), chunk.tokens))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateQuestions(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []int
+		total  int
+		want   []int
+	}{
+		{name: "proportional to content", tokens: []int{100, 300}, total: 10, want: []int{3, 7}},
+		// 1 each, then 7 split 7/3, 7/3, 7/3: the remainders are equal and go to the first
+		{name: "rounded to the total", tokens: []int{100, 100, 100}, total: 10, want: []int{4, 3, 3}},
+		// 1 each, then 3 split 0.3, 0.6, 2.1: the spare question goes to the 0.6
+		{name: "largest remainders", tokens: []int{10, 20, 70}, total: 6, want: []int{1, 2, 3}},
+		{name: "at least one each", tokens: []int{1, 1000}, total: 5, want: []int{1, 4}},
+		// 2 split 0.4, 1.2, 0.4: the spare question goes to the first 0.4
+		{name: "fewer questions than chunks", tokens: []int{100, 300, 100}, total: 2, want: []int{1, 1, 0}},
+		{name: "no questions", tokens: []int{100, 300}, total: 0, want: []int{0, 0}},
+		{name: "zero-size chunks", tokens: []int{0, 0, 0}, total: 7, want: []int{3, 2, 2}},
+		{name: "some zero-size chunks", tokens: []int{0, 100}, total: 5, want: []int{1, 4}},
+		{name: "no chunks", tokens: nil, total: 5, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := make([]contentChunk, len(tt.tokens))
+			for i, tokens := range tt.tokens {
+				chunks[i].tokens = tokens
+			}
+			allocateQuestions(chunks, tt.total)
+
+			got := []int{}
+			sum := 0
+			for _, chunk := range chunks {
+				got = append(got, chunk.questions)
+				sum += chunk.questions
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("questions = %v, want %v", got, tt.want)
+			}
+			if len(chunks) > 0 && sum != tt.total {
+				t.Errorf("allocated %d questions, want exactly %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestNewGenerationPlanRejectsNegativeCounts(t *testing.T) {
+	course := &Course{Chapters: []CourseChapter{{Title: "A", Lessons: []CourseLesson{lesson("a1", 10)}}}}
+	if _, err := newGenerationPlan(course, -1, nil, ""); err == nil {
+		t.Errorf("no error for -1 questions")
+	}
+}
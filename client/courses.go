@@ -1,10 +1,9 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
-	"sync"
+	"strconv"
 )
 
 type CourseLesson struct {
@@ -53,67 +52,65 @@ func (l *CourseLesson) setContent(l2 *CourseLesson) {
 }
 
 type CourseChapter struct {
+	UUID    string         `json:"UUID"`
+	Slug    string         `json:"Slug"`
+	Title   string         `json:"Title"`
 	Lessons []CourseLesson `json:"Lessons"`
 }
 
 type Course struct {
-	UUID         string `json:"UUID"`
-	Slug         string `json:"Slug"`
-	Descriptiopn string `json:"ShortDescriptiopn"`
-	Title        string `json:"Title"`
-	Chapters     []struct {
-		Lessons []CourseLesson `json:"Lessons"`
-	} `json:"Chapters"`
+	UUID         string          `json:"UUID"`
+	Slug         string          `json:"Slug"`
+	Descriptiopn string          `json:"ShortDescriptiopn"`
+	Title        string          `json:"Title"`
+	Chapters     []CourseChapter `json:"Chapters"`
 }
 
-func (c *Course) GetLessons() []*CourseLesson {
-	var lessons []*CourseLesson
-	for chapter := range c.Chapters {
-		for lesson := range c.Chapters[chapter].Lessons {
-			lessons = append(lessons, &c.Chapters[chapter].Lessons[lesson])
+// FindChapter returns the chapter matching a UUID, a slug or a 1-based number
+func (c *Course) FindChapter(ref string) (*CourseChapter, error) {
+	for i := range c.Chapters {
+		chapter := &c.Chapters[i]
+		if chapter.UUID == ref || chapter.Slug == ref || strconv.Itoa(i+1) == ref {
+			return chapter, nil
 		}
 	}
-	return lessons
+	return nil, fmt.Errorf("chapter %q not found in %s", ref, c.Title)
 }
 
-func downloadLessonsContent(c *Course) {
-	var wg sync.WaitGroup
-	for _, lesson := range c.GetLessons() {
-		wg.Add(1)
-		go func(l *CourseLesson) {
-			defer wg.Done()
-			resp, err := fetchWithAuth("GET", fmt.Sprintf("/v1/static/lessons/%s", l.UUID))
-			if err != nil {
-				log.Printf("Error fetching lessons %s: %s", l.UUID, err)
-				return
-			}
-			var lr LessonResp
-			// log.Printf("%s\n", resp)
-			err = json.Unmarshal(resp, &lr)
-			if err != nil {
-				log.Printf("Error parsing lesson %s: %s", l.UUID, err)
-				return
-			}
-			l.setContent(&lr.Lesson)
-			if l.Content == "" {
-				log.Printf("Couldn't parse content from %s\n", resp)
+// WithChapter returns a copy of the course holding only the given chapter
+func (c *Course) WithChapter(chapter *CourseChapter) *Course {
+	course := *c
+	course.Chapters = []CourseChapter{*chapter}
+	return &course
+}
+
+// GetChapter returns the chapter a lesson belongs to
+func (c *Course) GetChapter(lesson *CourseLesson) *CourseChapter {
+	for i := range c.Chapters {
+		for j := range c.Chapters[i].Lessons {
+			if &c.Chapters[i].Lessons[j] == lesson {
+				return &c.Chapters[i]
 			}
-		}(lesson)
+		}
 	}
-	wg.Wait()
+	return nil
 }
 
-func FetchCourseAndLessons(courseUUID string) (*Course, error) {
-	resp, err := fetchWithAuth("GET", fmt.Sprintf("/v1/courses/%s", courseUUID))
-	if err != nil {
-		return nil, err
+func (c *Course) GetLessons() []*CourseLesson {
+	var lessons []*CourseLesson
+	for chapter := range c.Chapters {
+		for lesson := range c.Chapters[chapter].Lessons {
+			lessons = append(lessons, &c.Chapters[chapter].Lessons[lesson])
+		}
 	}
+	return lessons
+}
 
-	var c Course
-	err = json.Unmarshal(resp, &c)
-	if err != nil {
+// FetchLessonContent downloads a single lesson with its content
+func FetchLessonContent(lessonUUID string) (*CourseLesson, error) {
+	lesson := &CourseLesson{UUID: lessonUUID}
+	if err := fetchLessonContent(context.Background(), lesson); err != nil {
 		return nil, err
 	}
-	downloadLessonsContent(&c)
-	return &c, nil
+	return lesson, nil
 }
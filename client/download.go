@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultDownloadConcurrency is how many lessons are downloaded at the same time
+const DefaultDownloadConcurrency = 4
+
+// maxDownloadAttempts is how many times a lesson is requested when the API is
+// rate limiting or failing
+const maxDownloadAttempts = 4
+
+// downloadRetryDelay is the wait before the first retry, doubled on each one
+var downloadRetryDelay = time.Second
+
+// DownloadProgress counts the lessons of a course download
+type DownloadProgress struct {
+	Total      int
+	Downloaded int
+	Failed     int
+	// Skipped lessons are up to date, or were not downloaded because the
+	// download was canceled
+	Skipped int
+}
+
+// Done returns the number of lessons the download is over with
+func (p DownloadProgress) Done() int {
+	return p.Downloaded + p.Failed + p.Skipped
+}
+
+// DownloadError lists the lessons that could not be downloaded
+type DownloadError struct {
+	// Failed holds an error per lesson, naming it
+	Failed []error
+	// Canceled is set when the download was stopped before the end
+	Canceled bool
+}
+
+func (e *DownloadError) Error() string {
+	var msg strings.Builder
+	if e.Canceled {
+		msg.WriteString("download canceled")
+	}
+	if len(e.Failed) > 0 {
+		if e.Canceled {
+			msg.WriteString(", and ")
+		}
+		fmt.Fprintf(&msg, "failed to download %d lessons:", len(e.Failed))
+		for _, err := range e.Failed {
+			msg.WriteString("\n  " + strings.ReplaceAll(err.Error(), "\n", " "))
+		}
+	}
+	return msg.String()
+}
+
+func (e *DownloadError) Unwrap() []error {
+	if e.Canceled {
+		return append([]error{context.Canceled}, e.Failed...)
+	}
+	return e.Failed
+}
+
+func downloadConcurrency() int {
+	if concurrency := viper.GetInt("download.concurrency"); concurrency > 0 {
+		return concurrency
+	}
+	return DefaultDownloadConcurrency
+}
+
+// downloadLessonsContent fills in the content of the lessons with a pool of
+// download.concurrency workers, telling onProgress, when set, how far along
+// it is. skipped is the number of lessons of the course already up to date.
+// It returns a *DownloadError when some lessons are left without content.
+func downloadLessonsContent(ctx context.Context, lessons []*CourseLesson, skipped int, onProgress func(DownloadProgress)) error {
+	var mu sync.Mutex
+	progress := DownloadProgress{Total: len(lessons) + skipped, Skipped: skipped}
+	// One error per lesson, to list the failed ones in the order of the course
+	errs := make([]error, len(lessons))
+	report := func() {
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+	report()
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range lessons {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range min(downloadConcurrency(), len(lessons)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				err := fetchLessonContent(ctx, lessons[i])
+				mu.Lock()
+				switch {
+				case err == nil:
+					progress.Downloaded++
+				case ctx.Err() != nil:
+					// Canceled, counted with the lessons never started below
+				default:
+					progress.Failed++
+					errs[i] = fmt.Errorf("%s: %w", lessons[i].Slug, err)
+				}
+				report()
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	canceled := ctx.Err() != nil
+	if canceled {
+		progress.Skipped = progress.Total - progress.Downloaded - progress.Failed
+		report()
+	}
+	if canceled || len(failed) > 0 {
+		return &DownloadError{Failed: failed, Canceled: canceled}
+	}
+	return nil
+}
+
+// fetchLessonContent fills in the content of a lesson from its readme, trying
+// again with an exponential backoff while the API answers 429 or 5xx
+func fetchLessonContent(ctx context.Context, l *CourseLesson) error {
+	url := fmt.Sprintf("/v1/static/lessons/%s", l.UUID)
+	delay := downloadRetryDelay
+	for attempt := 1; ; attempt++ {
+		resp, code, err := fetchWithAuthContext(ctx, "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("error fetching lesson %s: %w", l.UUID, err)
+		}
+		retry := code == http.StatusTooManyRequests || code >= 500
+		if retry && attempt < maxDownloadAttempts {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay *= 2
+			continue
+		}
+		if err := checkStatus("GET", url, code, resp); err != nil {
+			if retry {
+				return fmt.Errorf("error fetching lesson %s after %d attempts: %w", l.UUID, attempt, err)
+			}
+			return fmt.Errorf("error fetching lesson %s: %w", l.UUID, err)
+		}
+
+		var lr LessonResp
+		if err := json.Unmarshal(resp, &lr); err != nil {
+			return fmt.Errorf("error parsing lesson %s: %w", l.UUID, err)
+		}
+		l.setContent(&lr.Lesson)
+		if l.Content == "" {
+			return fmt.Errorf("lesson %s has no readme", l.UUID)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func fastRetries(t *testing.T) {
+	previous := downloadRetryDelay
+	downloadRetryDelay = time.Millisecond
+	t.Cleanup(func() { downloadRetryDelay = previous })
+}
+
+func TestFetchLessonContentRetries(t *testing.T) {
+	fastRetries(t)
+	tests := []struct {
+		name     string
+		statuses []int
+		want     string
+		requests int
+	}{
+		{name: "rate limited once", statuses: []int{http.StatusTooManyRequests, http.StatusOK}, requests: 2},
+		{name: "failing", statuses: []int{http.StatusInternalServerError}, want: fmt.Sprintf("after %d attempts", maxDownloadAttempts), requests: maxDownloadAttempts},
+		{name: "not found", statuses: []int{http.StatusNotFound}, want: "404", requests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			fakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				// The last status is repeated once the others are used up
+				status := tt.statuses[min(requests, len(tt.statuses)-1)]
+				requests++
+				if status != http.StatusOK {
+					http.Error(w, http.StatusText(status), status)
+					return
+				}
+				w.Write(lessonResponse("readme"))
+			})
+
+			lesson := &CourseLesson{UUID: "l1"}
+			err := fetchLessonContent(context.Background(), lesson)
+			if tt.want == "" && (err != nil || lesson.Content != "readme") {
+				t.Errorf("content %q, error %v; want the readme", lesson.Content, err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+			if requests != tt.requests {
+				t.Errorf("made %d requests, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
+
+func testLessons(n int) []*CourseLesson {
+	lessons := make([]*CourseLesson, n)
+	for i := range lessons {
+		lessons[i] = &CourseLesson{UUID: fmt.Sprintf("l%d", i+1), Slug: fmt.Sprintf("lesson-%d", i+1)}
+	}
+	return lessons
+}
+
+func TestDownloadLessonsContentCanceled(t *testing.T) {
+	fakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(lessonResponse("readme"))
+	})
+	viper.Set("download.concurrency", 1)
+	t.Cleanup(func() { viper.Set("download.concurrency", nil) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var last DownloadProgress
+	err := downloadLessonsContent(ctx, testLessons(5), 2, func(p DownloadProgress) {
+		last = p
+		// Stop once the first lesson is downloaded
+		if p.Downloaded == 1 {
+			cancel()
+		}
+	})
+
+	var downloadErr *DownloadError
+	if !errors.As(err, &downloadErr) || !downloadErr.Canceled || len(downloadErr.Failed) != 0 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want the download canceled", err)
+	}
+	want := DownloadProgress{Total: 7, Downloaded: 1, Skipped: 6}
+	if last != want {
+		t.Errorf("progress = %+v, want %+v", last, want)
+	}
+}
+
+func TestDownloadLessonsContentConcurrency(t *testing.T) {
+	var running, most atomic.Int32
+	var mu sync.Mutex
+	fakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		now := running.Add(1)
+		defer running.Add(-1)
+		mu.Lock()
+		most.Store(max(most.Load(), now))
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		w.Write(lessonResponse("readme"))
+	})
+	viper.Set("download.concurrency", 3)
+	t.Cleanup(func() { viper.Set("download.concurrency", nil) })
+
+	lessons := testLessons(12)
+	var last DownloadProgress
+	if err := downloadLessonsContent(context.Background(), lessons, 0, func(p DownloadProgress) { last = p }); err != nil {
+		t.Fatalf("downloadLessonsContent: %v", err)
+	}
+	if got := most.Load(); got > 3 {
+		t.Errorf("%d lessons downloaded at the same time, want at most 3", got)
+	}
+	if last != (DownloadProgress{Total: 12, Downloaded: 12}) {
+		t.Errorf("progress = %+v, want the 12 lessons downloaded", last)
+	}
+	for _, l := range lessons {
+		if l.Content != "readme" {
+			t.Errorf("%s has no content", l.Slug)
+		}
+	}
+}
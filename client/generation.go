@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	dao "github.com/bootdotdev/bootdev/db"
+)
+
+// maxGenerateAttempts caps the calls made for the questions of one chunk: the
+// first one, then follow-ups for the questions missing after a failed call or
+// rejected questions
+const maxGenerateAttempts = 3
+
+// retryDelay is the wait before the first follow-up call, doubled for each next one
+var retryDelay = 2 * time.Second
+
+// ask sends a prompt to the language model, it is askLLM but for tests
+var ask = askLLM
+
+// GenerationReport tells what came of the questions asked to the language model
+type GenerationReport struct {
+	Requested int
+	Accepted  int
+	Rejected  []RejectedQuestion
+	Calls     int
+	// Errors are the calls that failed or returned something that wasn't questions
+	Errors []error
+}
+
+// RejectedQuestion is a generated question left out because it is invalid
+type RejectedQuestion struct {
+	Chunk    string
+	Question string
+	Reason   string
+}
+
+// Missing is how many of the requested questions could not be generated
+func (r *GenerationReport) Missing() int {
+	return r.Requested - r.Accepted
+}
+
+func (r *GenerationReport) add(other GenerationReport) {
+	r.Requested += other.Requested
+	r.Accepted += other.Accepted
+	r.Rejected = append(r.Rejected, other.Rejected...)
+	r.Calls += other.Calls
+	r.Errors = append(r.Errors, other.Errors...)
+}
+
+// questionsSchema is the structured output asked for generated questions
+func questionsSchema(questionTypes []string) *Schema {
+	str := map[string]any{"type": "string"}
+	strs := map[string]any{"type": "array", "items": str}
+	return &Schema{
+		Name:        "submit_questions",
+		Description: "Submit the generated quiz questions",
+		Properties: map[string]any{
+			"questions": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"type":        map[string]any{"type": "string", "enum": questionTypes},
+						"question":    str,
+						"choices":     strs,
+						"answer":      str,
+						"answers":     strs,
+						"explanation": str,
+						"rubric":      str,
+						"lesson":      map[string]any{"type": "integer"},
+					},
+					"required": []string{"type", "question", "choices", "answer", "explanation", "lesson"},
+				},
+			},
+		},
+		Required: []string{"questions"},
+	}
+}
+
+// generateQuestions asks the configured QuestionGenerator for quiz questions,
+// keeping the valid ones and asking again for the missing number with a
+// growing delay, up to maxGenerateAttempts calls
+func generateQuestions(call llmCall, tmpl *template.Template, data PromptData, numQuestions int, questionTypes []string) ([]GeneratedQuestion, GenerationReport) {
+	report := GenerationReport{Requested: numQuestions}
+
+	accepted := []GeneratedQuestion{}
+	delay := retryDelay
+	for attempt := 1; len(accepted) < numQuestions && attempt <= maxGenerateAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		data.Count = numQuestions - len(accepted)
+		prompt, err := renderPrompt(tmpl, data)
+		if err != nil {
+			report.Errors = append(report.Errors, err)
+			break
+		}
+
+		report.Calls++
+		responseText, runID, err := ask(call, CompletionRequest{
+			Prompt:      prompt,
+			MaxTokens:   generationMaxTokens(),
+			Temperature: generationTemperature(),
+			Schema:      questionsSchema(questionTypes),
+		})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("call %d: %w", attempt, err))
+			continue
+		}
+		questions, err := parseGeneratedQuestions(responseText)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("call %d (run #%d, see quiz-mgmt reparse): %w", attempt, runID, err))
+			continue
+		}
+
+		for _, q := range questions {
+			if len(accepted) == numQuestions {
+				report.Rejected = append(report.Rejected, RejectedQuestion{Question: q.Question, Reason: "more questions than asked"})
+				continue
+			}
+			if err := q.validate(questionTypes); err != nil {
+				report.Rejected = append(report.Rejected, RejectedQuestion{Question: q.Question, Reason: err.Error()})
+				continue
+			}
+			q.runID = runID
+			accepted = append(accepted, q)
+			data.ExistingQuestions = append(data.ExistingQuestions, q.Question)
+		}
+	}
+	report.Accepted = len(accepted)
+	return accepted, report
+}
+
+// parseGeneratedQuestions reads the questions of a response, as the object of
+// the structured output or as a bare array, in a Markdown code block or not,
+// ignoring any text around it
+func parseGeneratedQuestions(responseText string) ([]GeneratedQuestion, error) {
+	text := strings.TrimSpace(responseText)
+	if start := strings.IndexAny(text, "{["); start > 0 {
+		text = text[start:]
+	}
+	if end := strings.LastIndexAny(text, "}]"); end >= 0 {
+		text = text[:end+1]
+	}
+
+	var questions []GeneratedQuestion
+	if strings.HasPrefix(text, "[") {
+		if err := json.Unmarshal([]byte(text), &questions); err != nil {
+			return nil, fmt.Errorf("failed to parse generated questions: %w", err)
+		}
+		return questions, nil
+	}
+
+	var response struct {
+		Questions []GeneratedQuestion `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse generated questions: %w", err)
+	}
+	return response.Questions, nil
+}
+
+// validate checks that a generated question is of a requested type, has
+// distinct choices and can be asked and graded
+func (q GeneratedQuestion) validate(questionTypes []string) error {
+	if q.Type != "" && !slices.Contains(questionTypes, q.Type) {
+		return fmt.Errorf("question type %q was not requested", q.Type)
+	}
+
+	seen := map[string]bool{}
+	for _, choice := range q.Choices {
+		key := strings.ToLower(strings.TrimSpace(choice))
+		if seen[key] {
+			return fmt.Errorf("choice %q is repeated", choice)
+		}
+		seen[key] = true
+	}
+
+	question, err := q.toQuestion(questionTypes[0])
+	if err != nil {
+		return err
+	}
+	file := question.ToFile()
+	return file.Validate()
+}
+
+// ReparseRun parses the raw response of a generation run again, without
+// calling the language model, and returns its valid questions linked to their
+// lesson and to the run
+func ReparseRun(run *dao.GenerationRun) ([]dao.Question, GenerationReport, error) {
+	if run.Kind != "generate" {
+		return nil, GenerationReport{}, fmt.Errorf("run #%d is a %s call, only generate runs can be reparsed", run.ID, run.Kind)
+	}
+	generated, err := parseGeneratedQuestions(run.RawResponse)
+	if err != nil {
+		return nil, GenerationReport{}, fmt.Errorf("run #%d: %w", run.ID, err)
+	}
+
+	// The requested types are not recorded, any type that can be generated is
+	// fine, questions without a type being of the default one
+	questionTypes := slices.Clone(DefaultQuestionTypes)
+	for t := range questionTypeInstructions {
+		if !slices.Contains(questionTypes, t) {
+			questionTypes = append(questionTypes, t)
+		}
+	}
+
+	report := GenerationReport{Requested: len(generated)}
+	questions := []dao.Question{}
+	chunk := fmt.Sprintf("run #%d", run.ID)
+	for _, q := range generated {
+		if err := q.validate(questionTypes); err != nil {
+			report.Rejected = append(report.Rejected, RejectedQuestion{Chunk: chunk, Question: q.Question, Reason: err.Error()})
+			continue
+		}
+		q.runID = run.ID
+		question, err := q.toSourcedQuestion(DefaultQuestionTypes[0], run.Lessons)
+		if err != nil {
+			report.Rejected = append(report.Rejected, RejectedQuestion{Chunk: chunk, Question: q.Question, Reason: err.Error()})
+			continue
+		}
+		question.CourseUUID = run.CourseUUID
+		questions = append(questions, question)
+	}
+	report.Accepted = len(questions)
+	return questions, report, nil
+}
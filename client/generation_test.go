@@ -0,0 +1,264 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+
+	dao "github.com/bootdotdev/bootdev/db"
+)
+
+func TestParseGeneratedQuestions(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "bare array",
+			response: `[{"type":"fill_blank","question":"Q1","answer":"A1"},{"question":"Q2","answer":"A2"}]`,
+			want:     []string{"Q1", "Q2"},
+		},
+		{
+			name:     "object",
+			response: `{"questions":[{"question":"Q1","answer":"A1"}]}`,
+			want:     []string{"Q1"},
+		},
+		{
+			name:     "object in a fenced block",
+			response: "Here are your questions:\n```json\n{\"questions\": [{\"question\": \"Q1\", \"answer\": \"A1\"}]}\n```\nGood luck!",
+			want:     []string{"Q1"},
+		},
+		{
+			name:     "bare array in a fenced block",
+			response: "```\n[{\"question\": \"Q1\", \"answer\": \"A1\"}]\n```",
+			want:     []string{"Q1"},
+		},
+		{name: "no questions", response: `{"questions":[]}`, want: []string{}},
+		{name: "not JSON", response: "I can't help with that", wantErr: true},
+		{name: "truncated", response: `{"questions":[{"question":"Q1","ans`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			questions, err := parseGeneratedQuestions(tt.response)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("no error, got %+v", questions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			got := []string{}
+			for _, q := range questions {
+				got = append(got, q.Question)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGeneratedQuestion(t *testing.T) {
+	multipleChoice := []string{"multiple_choice"}
+	tests := []struct {
+		name     string
+		question GeneratedQuestion
+		types    []string
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			question: GeneratedQuestion{Type: "multiple_choice", Question: "Q", Choices: []string{"a", "b", "c", "d"}, Answer: "b"},
+			types:    multipleChoice,
+		},
+		{
+			name:     "type left out",
+			question: GeneratedQuestion{Question: "Q", Choices: []string{"a", "b"}, Answer: "a"},
+			types:    multipleChoice,
+		},
+		{
+			name:     "repeated choice",
+			question: GeneratedQuestion{Type: "multiple_choice", Question: "Q", Choices: []string{"a", "b", " A", "d"}, Answer: "b"},
+			types:    multipleChoice,
+			wantErr:  "is repeated",
+		},
+		{
+			name:     "answer not a choice",
+			question: GeneratedQuestion{Type: "multiple_choice", Question: "Q", Choices: []string{"a", "b", "c", "d"}, Answer: "e"},
+			types:    multipleChoice,
+			wantErr:  "not one of the choices",
+		},
+		{
+			name:     "multi_select answer not a choice",
+			question: GeneratedQuestion{Type: "multi_select", Question: "Q", Choices: []string{"a", "b", "c"}, Answers: []string{"a", "z"}},
+			types:    []string{"multi_select"},
+			wantErr:  "not one of the choices",
+		},
+		{
+			name:     "unrequested type",
+			question: GeneratedQuestion{Type: "fill_blank", Question: "Q", Answer: "a"},
+			types:    multipleChoice,
+			wantErr:  "was not requested",
+		},
+		{
+			name:     "requested type",
+			question: GeneratedQuestion{Type: "fill_blank", Question: "Q", Answer: "a"},
+			types:    []string{"multiple_choice", "fill_blank"},
+		},
+		{
+			name:     "no answer",
+			question: GeneratedQuestion{Type: "fill_blank", Question: "Q"},
+			types:    []string{"fill_blank"},
+			wantErr:  "needs an answer",
+		},
+		{
+			name:     "no question",
+			question: GeneratedQuestion{Type: "multiple_choice", Choices: []string{"a", "b"}, Answer: "a"},
+			types:    multipleChoice,
+			wantErr:  "question text is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.question.validate(tt.types)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// stubAsk replaces the calls to the language model with the given responses,
+// in order, and returns the prompts it was sent
+func stubAsk(t *testing.T, responses ...string) *[]string {
+	t.Helper()
+	prompts := []string{}
+	previousAsk, previousDelay := ask, retryDelay
+	ask = func(call llmCall, req CompletionRequest) (string, int64, error) {
+		prompts = append(prompts, req.Prompt)
+		if len(prompts) > len(responses) {
+			t.Fatalf("unexpected call %d", len(prompts))
+		}
+		return responses[len(prompts)-1], int64(len(prompts)), nil
+	}
+	retryDelay = 0
+	t.Cleanup(func() { ask, retryDelay = previousAsk, previousDelay })
+	return &prompts
+}
+
+func multipleChoiceJSON(question, answer string, choices ...string) string {
+	return fmt.Sprintf(`{"type":"multiple_choice","question":%q,"choices":["%s"],"answer":%q}`, question, strings.Join(choices, `","`), answer)
+}
+
+func TestGenerateQuestionsAsksForTheMissingOnes(t *testing.T) {
+	prompts := stubAsk(t,
+		// 1 valid, 1 with an answer not among its choices, 1 repeating a choice
+		`{"questions":[`+strings.Join([]string{
+			multipleChoiceJSON("Q1", "a", "a", "b", "c", "d"),
+			multipleChoiceJSON("Q2", "e", "a", "b", "c", "d"),
+			multipleChoiceJSON("Q3", "a", "a", "b", "b", "d"),
+		}, ",")+`]}`,
+		// 1 valid and 2 more than asked
+		`{"questions":[`+strings.Join([]string{
+			multipleChoiceJSON("Q4", "a", "a", "b", "c", "d"),
+			multipleChoiceJSON("Q5", "a", "a", "b", "c", "d"),
+			multipleChoiceJSON("Q6", "a", "a", "b", "c", "d"),
+		}, ",")+`]}`,
+	)
+	tmpl := template.Must(template.New("prompt").Parse("Ask {{.Count}} questions, not {{range .ExistingQuestions}}{{.}};{{end}}"))
+
+	questions, report := generateQuestions(llmCall{kind: "generate"}, tmpl, PromptData{}, 3, []string{"multiple_choice"})
+
+	if len(*prompts) != 2 {
+		t.Fatalf("made %d calls, want 2: %q", len(*prompts), *prompts)
+	}
+	if want := "Ask 3 questions, not "; (*prompts)[0] != want {
+		t.Errorf("first prompt = %q, want %q", (*prompts)[0], want)
+	}
+	// The follow-up only asks for the missing questions, and not to repeat the accepted one
+	if want := "Ask 2 questions, not Q1;"; (*prompts)[1] != want {
+		t.Errorf("follow-up prompt = %q, want %q", (*prompts)[1], want)
+	}
+
+	got := []string{}
+	for _, q := range questions {
+		got = append(got, fmt.Sprintf("%s#%d", q.Question, q.runID))
+	}
+	if strings.Join(got, " ") != "Q1#1 Q4#2 Q5#2" {
+		t.Errorf("accepted %q, want Q1 from run 1 and Q4, Q5 from run 2", got)
+	}
+	if report.Requested != 3 || report.Accepted != 3 || report.Calls != 2 || report.Missing() != 0 || len(report.Errors) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	reasons := []string{}
+	for _, r := range report.Rejected {
+		reasons = append(reasons, r.Question+": "+r.Reason)
+	}
+	want := []string{
+		`Q2: answer "e" is not one of the choices`,
+		`Q3: choice "b" is repeated`,
+		"Q6: more questions than asked",
+	}
+	if strings.Join(reasons, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rejected %q, want %q", reasons, want)
+	}
+}
+
+func TestGenerateQuestionsGivesUpAfterMaxAttempts(t *testing.T) {
+	prompts := stubAsk(t, "not JSON", `{"questions":[]}`, `{"questions":[`+multipleChoiceJSON("Q1", "a", "a", "b")+`]}`)
+	tmpl := template.Must(template.New("prompt").Parse("Ask {{.Count}}"))
+
+	questions, report := generateQuestions(llmCall{kind: "generate"}, tmpl, PromptData{}, 2, []string{"multiple_choice"})
+
+	if len(*prompts) != maxGenerateAttempts {
+		t.Errorf("made %d calls, want %d", len(*prompts), maxGenerateAttempts)
+	}
+	if len(questions) != 1 || report.Missing() != 1 || len(report.Errors) != 1 {
+		t.Errorf("got %d questions, report %+v; want 1 question, 1 missing and the parse error", len(questions), report)
+	}
+}
+
+func TestReparseRun(t *testing.T) {
+	run := &dao.GenerationRun{
+		ID:         4,
+		Kind:       "generate",
+		CourseUUID: "course",
+		RawResponse: "Here you go:\n```json\n{\"questions\":[" + strings.Join([]string{
+			multipleChoiceJSON("Q1", "a", "a", "b"),
+			multipleChoiceJSON("Q2", "e", "a", "b"),
+			`{"type":"ordering","question":"Q3","choices":["x","y"],"lesson":2}`,
+		}, ",") + "]}\n```",
+		Lessons: []dao.LessonRef{{LessonUUID: "l1"}, {LessonUUID: "l2", ChapterSlug: "c1"}},
+	}
+
+	questions, report, err := ReparseRun(run)
+	if err != nil {
+		t.Fatalf("ReparseRun: %v", err)
+	}
+	got := []string{}
+	for _, q := range questions {
+		got = append(got, fmt.Sprintf("%s %s %s %s #%d", q.QuestionText, q.QuestionType, q.CourseUUID, q.LessonUUID, q.GenerationRunID))
+	}
+	want := []string{"Q1 multiple_choice course  #4", "Q3 ordering course l2 #4"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("questions = %q, want %q", got, want)
+	}
+	if report.Requested != 3 || report.Accepted != 2 || len(report.Rejected) != 1 || report.Rejected[0].Chunk != "run #4" {
+		t.Errorf("report = %+v, want Q2 rejected", report)
+	}
+
+	if _, _, err := ReparseRun(&dao.GenerationRun{ID: 5, Kind: "grade"}); err == nil {
+		t.Errorf("reparsed a grade run")
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	dao "github.com/bootdotdev/bootdev/db"
+)
+
+type freeResponseGrade struct {
+	Score    float64 `json:"score"`
+	Feedback string  `json:"feedback"`
+}
+
+// GradeFreeResponse asks the language model to grade a free_response answer
+// against the question's rubric and reference answer
+func GradeFreeResponse(q *dao.Question, answer string) (dao.Grade, error) {
+	if strings.TrimSpace(answer) == "" {
+		return dao.ScoreGrade(0, "No answer given."), nil
+	}
+
+	rubric := q.Rubric
+	if rubric == "" {
+		rubric = "The answer must convey the same key ideas as the reference answer."
+	}
+
+	prompt := fmt.Sprintf(`You are grading a student's answer to a quiz question. Compare the student's answer to the reference answer using the rubric. Judge the understanding of the concept, not the wording, spelling or grammar.
+
+Question:
+%s
+
+Reference answer:
+%s
+
+Rubric:
+%s
+
+Student's answer:
+%s
+
+Respond ONLY with a JSON object in this exact format, no additional text:
+{"score": 0.0, "feedback": "..."}
+
+"score" is a number from 0 (wrong or missing the point) to 1 (covers every point of the rubric). "feedback" is one to three sentences addressed to the student explaining what was right and what was missing.`, q.QuestionText, q.CorrectAnswer, rubric, answer)
+
+	responseText, _, err := askLLM(llmCall{kind: "grade", courseUUID: q.CourseUUID}, CompletionRequest{Prompt: prompt, MaxTokens: 1024})
+	if err != nil {
+		return dao.Grade{}, err
+	}
+
+	var result freeResponseGrade
+	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
+		return dao.Grade{}, fmt.Errorf("failed to parse grading response: %w", err)
+	}
+	return dao.ScoreGrade(result.Score, result.Feedback), nil
+}
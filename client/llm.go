@@ -0,0 +1,426 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/viper"
+)
+
+// QuestionGenerator is a language model that questions are generated, fixed
+// and graded with. It answers a single prompt with text.
+type QuestionGenerator interface {
+	// Name identifies the provider and the model in messages
+	Name() string
+	Complete(ctx context.Context, req CompletionRequest) (Completion, error)
+}
+
+// Completion is the answer of a QuestionGenerator to a prompt
+type Completion struct {
+	Text     string
+	Provider string
+	Model    string
+	// Token counts reported by the provider, used to estimate the cost
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// CompletionRequest is a prompt sent to a QuestionGenerator
+type CompletionRequest struct {
+	Prompt    string
+	MaxTokens int64
+	// Temperature is left to the default of the provider when nil
+	Temperature *float64
+	// Schema asks for a response that is a JSON object following it, through
+	// tool use or the structured output mode of the provider
+	Schema *Schema
+}
+
+// Schema describes the JSON object a response must be
+type Schema struct {
+	Name        string
+	Description string
+	Properties  map[string]any
+	Required    []string
+}
+
+// jsonSchema returns the schema as a JSON schema document
+func (s *Schema) jsonSchema() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": s.Properties,
+		"required":   s.Required,
+	}
+}
+
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderOllama    = "ollama"
+)
+
+// Providers are the supported values of the llm.provider config key
+var Providers = []string{ProviderAnthropic, ProviderOpenAI, ProviderOllama}
+
+const DefaultProvider = ProviderAnthropic
+
+var defaultModels = map[string]string{
+	ProviderAnthropic: string(anthropic.ModelClaude3_5Sonnet20241022),
+	ProviderOpenAI:    "gpt-4o-mini",
+	ProviderOllama:    "llama3.1",
+}
+
+var defaultBaseURLs = map[string]string{
+	ProviderOpenAI: "https://api.openai.com/v1",
+	ProviderOllama: "http://localhost:11434",
+}
+
+// apiKeyEnvs are read when llm.api_key is not set
+var apiKeyEnvs = map[string]string{
+	ProviderAnthropic: "ANTHROPIC_API_KEY",
+	ProviderOpenAI:    "OPENAI_API_KEY",
+}
+
+// NewQuestionGenerator returns the generator configured with the llm.provider,
+// llm.base_url, llm.model and llm.api_key config keys
+func NewQuestionGenerator() (QuestionGenerator, error) {
+	provider := strings.ToLower(viper.GetString("llm.provider"))
+	if provider == "" {
+		provider = DefaultProvider
+	}
+	model := viper.GetString("llm.model")
+	if model == "" {
+		model = defaultModels[provider]
+	}
+	baseURL := strings.TrimSuffix(viper.GetString("llm.base_url"), "/")
+	apiKey := viper.GetString("llm.api_key")
+	if apiKey == "" && apiKeyEnvs[provider] != "" {
+		apiKey = os.Getenv(apiKeyEnvs[provider])
+	}
+
+	switch provider {
+	case ProviderAnthropic:
+		if apiKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable or llm.api_key config not set")
+		}
+		return &anthropicGenerator{baseURL: baseURL, model: model, apiKey: apiKey}, nil
+	case ProviderOpenAI:
+		// Compatible servers running locally usually don't need a key
+		if apiKey == "" && baseURL == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY environment variable or llm.api_key config not set")
+		}
+		if baseURL == "" {
+			baseURL = defaultBaseURLs[provider]
+		}
+		return &openAIGenerator{baseURL: baseURL, model: model, apiKey: apiKey}, nil
+	case ProviderOllama:
+		if baseURL == "" {
+			baseURL = defaultBaseURLs[provider]
+		}
+		return &ollamaGenerator{baseURL: baseURL, model: model}, nil
+	}
+	return nil, fmt.Errorf("unknown llm provider %q, expected one of %s", provider, strings.Join(Providers, ", "))
+}
+
+// llmCall is what a call to the language model is for, to record it
+type llmCall struct {
+	kind       string
+	courseUUID string
+	// lessons are the lessons in the prompt, by number
+	lessons []dao.LessonRef
+}
+
+// askLLM sends a single prompt to the configured generator, records the call
+// with its raw response as a dao.GenerationRun, and returns the text of the
+// response and the ID of the run. When the run can't be recorded, the text is
+// still returned with a run ID of 0.
+func askLLM(call llmCall, req CompletionRequest) (string, int64, error) {
+	generator, err := NewQuestionGenerator()
+	if err != nil {
+		return "", 0, err
+	}
+	completion, err := generator.Complete(context.Background(), req)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", generator.Name(), err)
+	}
+
+	promptHash := sha256.Sum256([]byte(req.Prompt))
+	run := dao.GenerationRun{
+		Kind:         call.kind,
+		CourseUUID:   call.courseUUID,
+		Provider:     completion.Provider,
+		Model:        completion.Model,
+		PromptHash:   hex.EncodeToString(promptHash[:]),
+		RawResponse:  completion.Text,
+		InputTokens:  completion.InputTokens,
+		OutputTokens: completion.OutputTokens,
+		Cost:         estimateCost(completion),
+		Lessons:      call.lessons,
+	}
+	if err := dao.CreateGenerationRun(nil, &run); err != nil {
+		// The call is paid for already, its answer is still worth using
+		fmt.Fprintf(os.Stderr, "Warning: failed to record the call to %s: %v\n", generator.Name(), err)
+		run.ID = 0
+	}
+
+	if completion.Text == "" {
+		return "", run.ID, fmt.Errorf("%s: no text content in response", generator.Name())
+	}
+	return completion.Text, run.ID, nil
+}
+
+type anthropicGenerator struct {
+	baseURL string
+	model   string
+	apiKey  string
+}
+
+func (g *anthropicGenerator) Name() string {
+	return ProviderAnthropic + " " + g.model
+}
+
+func (g *anthropicGenerator) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	options := []option.RequestOption{option.WithAPIKey(g.apiKey)}
+	if g.baseURL != "" {
+		options = append(options, option.WithBaseURL(g.baseURL))
+	}
+	client := anthropic.NewClient(options...)
+
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.Model(g.model),
+		MaxTokens: req.MaxTokens,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(req.Prompt)),
+		},
+	}
+	if req.Temperature != nil {
+		params.Temperature = anthropic.Float(*req.Temperature)
+	}
+	if req.Schema != nil {
+		// Claude answers with structured output by calling a tool taking it as input
+		tool := anthropic.ToolUnionParamOfTool(anthropic.ToolInputSchemaParam{
+			Properties: req.Schema.Properties,
+			Required:   req.Schema.Required,
+		}, req.Schema.Name)
+		tool.OfTool.Description = anthropic.String(req.Schema.Description)
+		params.Tools = []anthropic.ToolUnionParam{tool}
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(req.Schema.Name)
+	}
+
+	resp, err := client.Messages.New(ctx, params)
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to call Claude API: %w", err)
+	}
+
+	completion := Completion{
+		Provider:     ProviderAnthropic,
+		Model:        string(resp.Model),
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}
+	// Extract text, or the input of the tool call, from the response
+	for _, content := range resp.Content {
+		switch content.Type {
+		case "tool_use":
+			completion.Text = string(content.AsToolUse().Input)
+			return completion, nil
+		case "text":
+			completion.Text += content.AsText().Text
+		}
+	}
+	return completion, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIGenerator talks to the chat completions endpoint of OpenAI, or of any
+// server compatible with it (vLLM, LM Studio, llama.cpp, OpenRouter...)
+type openAIGenerator struct {
+	baseURL string
+	model   string
+	apiKey  string
+}
+
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []chatMessage         `json:"messages"`
+	MaxTokens      int64                 `json:"max_tokens,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string         `json:"name"`
+		Schema map[string]any `json:"schema"`
+	} `json:"json_schema"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (g *openAIGenerator) Name() string {
+	return ProviderOpenAI + " " + g.model
+}
+
+func (g *openAIGenerator) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	body := openAIRequest{
+		Model:       g.model,
+		Messages:    []chatMessage{{Role: "user", Content: req.Prompt}},
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if req.Schema != nil {
+		body.ResponseFormat = &openAIResponseFormat{Type: "json_schema"}
+		body.ResponseFormat.JSONSchema.Name = req.Schema.Name
+		body.ResponseFormat.JSONSchema.Schema = req.Schema.jsonSchema()
+	}
+
+	var resp openAIResponse
+	if err := postJSON(ctx, g.baseURL+"/chat/completions", g.apiKey, body, &resp); err != nil {
+		return Completion{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return Completion{}, fmt.Errorf("no choices in response")
+	}
+	completion := Completion{
+		Text:         resp.Choices[0].Message.Content,
+		Provider:     ProviderOpenAI,
+		Model:        resp.Model,
+		InputTokens:  resp.Usage.PromptTokens,
+		OutputTokens: resp.Usage.CompletionTokens,
+	}
+	if completion.Model == "" {
+		completion.Model = g.model
+	}
+	return completion, nil
+}
+
+// ollamaGenerator talks to the chat endpoint of a local Ollama server
+type ollamaGenerator struct {
+	baseURL string
+	model   string
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	// Format is the JSON schema of the response, when one is required
+	Format  map[string]any `json:"format,omitempty"`
+	Options struct {
+		NumPredict  int64    `json:"num_predict,omitempty"`
+		Temperature *float64 `json:"temperature,omitempty"`
+	} `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message         chatMessage `json:"message"`
+	PromptEvalCount int64       `json:"prompt_eval_count"`
+	EvalCount       int64       `json:"eval_count"`
+}
+
+func (g *ollamaGenerator) Name() string {
+	return ProviderOllama + " " + g.model
+}
+
+func (g *ollamaGenerator) Complete(ctx context.Context, req CompletionRequest) (Completion, error) {
+	body := ollamaRequest{
+		Model:    g.model,
+		Messages: []chatMessage{{Role: "user", Content: req.Prompt}},
+	}
+	body.Options.NumPredict = req.MaxTokens
+	body.Options.Temperature = req.Temperature
+	if req.Schema != nil {
+		body.Format = req.Schema.jsonSchema()
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, g.baseURL+"/api/chat", "", body, &resp); err != nil {
+		return Completion{}, err
+	}
+	return Completion{
+		Text:         resp.Message.Content,
+		Provider:     ProviderOllama,
+		Model:        g.model,
+		InputTokens:  resp.PromptEvalCount,
+		OutputTokens: resp.EvalCount,
+	}, nil
+}
+
+// postJSON sends a JSON request to an HTTP backend and decodes its JSON response
+func postJSON(ctx context.Context, url, apiKey string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, errorMessage(respBody))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", url, err)
+	}
+	return nil
+}
+
+// errorMessage extracts the message of an error response, in the OpenAI
+// ({"error": {"message": ...}}) or Ollama ({"error": ...}) shapes
+func errorMessage(body []byte) string {
+	var openAIError struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &openAIError) == nil && openAIError.Error.Message != "" {
+		return openAIError.Error.Message
+	}
+	var ollamaError struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &ollamaError) == nil && ollamaError.Error != "" {
+		return ollamaError.Error
+	}
+	return strings.TrimSpace(string(body))
+}
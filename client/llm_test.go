@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/viper"
+)
+
+var testSchema = &Schema{
+	Name:        "questions",
+	Description: "The questions",
+	Properties:  map[string]any{"questions": map[string]any{"type": "array"}},
+	Required:    []string{"questions"},
+}
+
+// llmServer stands in for an HTTP backend: it checks each request with check
+// and answers with the given status and body
+func llmServer(t *testing.T, path string, status int, response string, check func(r *http.Request, body map[string]any)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != path {
+			t.Errorf("request to %s %s, want POST %s", r.Method, r.URL.Path, path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if check != nil {
+			check(r, body)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAIGeneratorComplete(t *testing.T) {
+	temperature := 0.2
+	srv := llmServer(t, "/chat/completions", 200,
+		`{"model":"gpt-4o-mini-2024-07-18","choices":[{"message":{"role":"assistant","content":"{\"questions\":[]}"}}],"usage":{"prompt_tokens":120,"completion_tokens":30}}`,
+		func(r *http.Request, body map[string]any) {
+			if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+				t.Errorf("Authorization = %q, want Bearer secret", auth)
+			}
+			if body["model"] != "gpt-4o-mini" {
+				t.Errorf("model = %v, want gpt-4o-mini", body["model"])
+			}
+			if body["max_tokens"] != 100.0 || body["temperature"] != 0.2 {
+				t.Errorf("max_tokens = %v, temperature = %v, want 100 and 0.2", body["max_tokens"], body["temperature"])
+			}
+			messages, _ := body["messages"].([]any)
+			if len(messages) != 1 || messages[0].(map[string]any)["content"] != "Ask me" {
+				t.Errorf("messages = %v, want the prompt as a single user message", messages)
+			}
+			format, _ := body["response_format"].(map[string]any)
+			schema, _ := format["json_schema"].(map[string]any)
+			if format["type"] != "json_schema" || schema["name"] != "questions" {
+				t.Errorf("response_format = %v, want the questions json_schema", format)
+			}
+			if s, _ := schema["schema"].(map[string]any); s["type"] != "object" || s["properties"] == nil {
+				t.Errorf("json_schema.schema = %v, want an object schema", schema["schema"])
+			}
+		})
+
+	g := &openAIGenerator{baseURL: srv.URL, model: "gpt-4o-mini", apiKey: "secret"}
+	c, err := g.Complete(context.Background(), CompletionRequest{Prompt: "Ask me", MaxTokens: 100, Temperature: &temperature, Schema: testSchema})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	want := Completion{Text: `{"questions":[]}`, Provider: ProviderOpenAI, Model: "gpt-4o-mini-2024-07-18", InputTokens: 120, OutputTokens: 30}
+	if c != want {
+		t.Errorf("Complete = %+v, want %+v", c, want)
+	}
+}
+
+func TestOpenAIGeneratorWithoutKey(t *testing.T) {
+	srv := llmServer(t, "/chat/completions", 200,
+		`{"choices":[{"message":{"role":"assistant","content":"Hello"}}]}`,
+		func(r *http.Request, body map[string]any) {
+			if auth, ok := r.Header["Authorization"]; ok {
+				t.Errorf("Authorization = %q, want none without a key", auth)
+			}
+			for _, key := range []string{"response_format", "temperature"} {
+				if _, ok := body[key]; ok {
+					t.Errorf("%s = %v, want it left out", key, body[key])
+				}
+			}
+		})
+
+	g := &openAIGenerator{baseURL: srv.URL, model: "local-model"}
+	c, err := g.Complete(context.Background(), CompletionRequest{Prompt: "Hi", MaxTokens: 10})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if c.Text != "Hello" || c.Model != "local-model" {
+		t.Errorf("Complete = %+v, want the text and the configured model", c)
+	}
+}
+
+func TestOpenAIGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     string
+	}{
+		{"error body", 429, `{"error":{"message":"Rate limit reached","type":"requests"}}`, "Rate limit reached"},
+		{"plain body", 502, "Bad Gateway", "502"},
+		{"no choices", 200, `{"choices":[]}`, "no choices"},
+		{"invalid JSON", 200, `not json`, "failed to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := llmServer(t, "/chat/completions", tt.status, tt.response, nil)
+			g := &openAIGenerator{baseURL: srv.URL, model: "gpt-4o-mini", apiKey: "secret"}
+			_, err := g.Complete(context.Background(), CompletionRequest{Prompt: "Hi"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Complete error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaGeneratorComplete(t *testing.T) {
+	srv := llmServer(t, "/api/chat", 200,
+		`{"model":"llama3.1","message":{"role":"assistant","content":"{\"questions\":[]}"},"done":true,"prompt_eval_count":80,"eval_count":20}`,
+		func(r *http.Request, body map[string]any) {
+			if auth, ok := r.Header["Authorization"]; ok {
+				t.Errorf("Authorization = %q, want none", auth)
+			}
+			if body["model"] != "llama3.1" || body["stream"] != false {
+				t.Errorf("model = %v, stream = %v, want llama3.1 without streaming", body["model"], body["stream"])
+			}
+			if format, _ := body["format"].(map[string]any); format["type"] != "object" || format["properties"] == nil {
+				t.Errorf("format = %v, want the JSON schema", body["format"])
+			}
+			if options, _ := body["options"].(map[string]any); options["num_predict"] != 100.0 {
+				t.Errorf("options = %v, want num_predict 100", options)
+			}
+		})
+
+	g := &ollamaGenerator{baseURL: srv.URL, model: "llama3.1"}
+	c, err := g.Complete(context.Background(), CompletionRequest{Prompt: "Ask me", MaxTokens: 100, Schema: testSchema})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	want := Completion{Text: `{"questions":[]}`, Provider: ProviderOllama, Model: "llama3.1", InputTokens: 80, OutputTokens: 20}
+	if c != want {
+		t.Errorf("Complete = %+v, want %+v", c, want)
+	}
+}
+
+func TestOllamaGeneratorError(t *testing.T) {
+	srv := llmServer(t, "/api/chat", 404, `{"error":"model \"llama9\" not found, try pulling it first"}`, nil)
+	g := &ollamaGenerator{baseURL: srv.URL, model: "llama9"}
+	_, err := g.Complete(context.Background(), CompletionRequest{Prompt: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "try pulling it first") || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Complete error = %v, want the status and the message of the server", err)
+	}
+}
+
+func TestNewQuestionGenerator(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	tests := []struct {
+		provider, baseURL, apiKey string
+		wantName                  string
+		wantErr                   bool
+	}{
+		{provider: "anthropic", wantErr: true},
+		{provider: "anthropic", apiKey: "k", wantName: "anthropic " + defaultModels[ProviderAnthropic]},
+		{provider: "openai", wantErr: true},
+		// Local OpenAI-compatible servers don't need a key
+		{provider: "openai", baseURL: "http://localhost:8000/v1", wantName: "openai gpt-4o-mini"},
+		{provider: "ollama", wantName: "ollama llama3.1"},
+		{provider: "other", wantErr: true},
+	}
+	for _, tt := range tests {
+		viper.Set("llm.provider", tt.provider)
+		viper.Set("llm.base_url", tt.baseURL)
+		viper.Set("llm.api_key", tt.apiKey)
+		viper.Set("llm.model", "")
+		g, err := NewQuestionGenerator()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s with key %q: no error", tt.provider, tt.apiKey)
+			}
+			continue
+		}
+		if err != nil || g.Name() != tt.wantName {
+			t.Errorf("%s: got %v, %v; want %s", tt.provider, g, err, tt.wantName)
+		}
+	}
+	viper.Set("llm.provider", "")
+	viper.Set("llm.base_url", "")
+	viper.Set("llm.api_key", "")
+}
+
+// useOllama points the configured generator at srv and the database at a new
+// file, which is returned open
+func useOllama(t *testing.T, srv *httptest.Server) *sql.DB {
+	t.Helper()
+	viper.Set("llm.provider", "ollama")
+	viper.Set("llm.base_url", srv.URL)
+	viper.Set("llm.model", "llama3.1")
+	viper.Set("db_path", filepath.Join(t.TempDir(), "test.db"))
+	t.Cleanup(func() {
+		viper.Set("llm.provider", "")
+		viper.Set("llm.base_url", "")
+		viper.Set("llm.model", "")
+		viper.Set("db_path", "")
+	})
+	db, err := dao.InitializeDatabase(dao.DefaultDBConfig())
+	if err != nil {
+		t.Fatalf("InitializeDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestAskLLMRecordsTheRun(t *testing.T) {
+	srv := llmServer(t, "/api/chat", 200, `{"model":"llama3.1","message":{"content":"Hello"},"prompt_eval_count":3,"eval_count":1}`, nil)
+	db := useOllama(t, srv)
+
+	text, runID, err := askLLM(llmCall{kind: "generate", courseUUID: "course"}, CompletionRequest{Prompt: "Hi"})
+	if err != nil || text != "Hello" || runID == 0 {
+		t.Fatalf("askLLM = %q, %d, %v; want the text and a run", text, runID, err)
+	}
+	var raw string
+	if err := db.QueryRow(`SELECT raw_response FROM generation_runs WHERE id = ?`, runID).Scan(&raw); err != nil || raw != "Hello" {
+		t.Errorf("recorded run = %q, %v; want the raw response", raw, err)
+	}
+}
+
+func TestAskLLMKeepsTheAnswerWhenTheRunIsNotRecorded(t *testing.T) {
+	srv := llmServer(t, "/api/chat", 200, `{"model":"llama3.1","message":{"content":"Hello"}}`, nil)
+	db := useOllama(t, srv)
+	if _, err := db.Exec(`DROP TABLE generation_runs`); err != nil {
+		t.Fatal(err)
+	}
+
+	text, runID, err := askLLM(llmCall{kind: "generate", courseUUID: "course"}, CompletionRequest{Prompt: "Hi"})
+	if err != nil || text != "Hello" || runID != 0 {
+		t.Errorf("askLLM = %q, %d, %v; want the text without a run", text, runID, err)
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// modelPrice is the cost of a model in USD per million tokens
+type modelPrice struct {
+	input  float64
+	output float64
+}
+
+// modelPrices are the list prices of hosted models, matched by the start of
+// the model name so dated versions share the price of their family
+var modelPrices = []struct {
+	prefix string
+	price  modelPrice
+}{
+	{"claude-3-5-haiku", modelPrice{0.8, 4}},
+	{"claude-3-haiku", modelPrice{0.25, 1.25}},
+	{"claude-3-5-sonnet", modelPrice{3, 15}},
+	{"claude-3-7-sonnet", modelPrice{3, 15}},
+	{"claude-sonnet-4", modelPrice{3, 15}},
+	{"claude-3-opus", modelPrice{15, 75}},
+	{"claude-opus-4", modelPrice{15, 75}},
+	{"gpt-4o-mini", modelPrice{0.15, 0.6}},
+	{"gpt-4o", modelPrice{2.5, 10}},
+	{"gpt-4.1-mini", modelPrice{0.4, 1.6}},
+	{"gpt-4.1", modelPrice{2, 8}},
+}
+
+// estimateCost returns the cost of a completion in USD, from the llm.price.input
+// and llm.price.output config keys when set, or from the list price of the model.
+// Local Ollama models and unknown models are free.
+func estimateCost(c Completion) float64 {
+	price := modelPrice{
+		input:  viper.GetFloat64("llm.price.input"),
+		output: viper.GetFloat64("llm.price.output"),
+	}
+	if !viper.IsSet("llm.price.input") && !viper.IsSet("llm.price.output") && c.Provider != ProviderOllama {
+		for _, p := range modelPrices {
+			if strings.HasPrefix(c.Model, p.prefix) {
+				price = p.price
+				break
+			}
+		}
+	}
+	return (float64(c.InputTokens)*price.input + float64(c.OutputTokens)*price.output) / 1e6
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+)
+
+// The prompt templates: questions generates the questions of a course,
+// regenerate replaces a flagged question
+const (
+	PromptQuestions  = "questions"
+	PromptRegenerate = "regenerate"
+)
+
+// PromptNames are the prompt templates that can be overridden
+var PromptNames = []string{PromptQuestions, PromptRegenerate}
+
+//go:embed prompts/questions.tmpl
+var defaultQuestionPrompt string
+
+//go:embed prompts/regenerate.tmpl
+var defaultRegeneratePrompt string
+
+var defaultPrompts = map[string]string{
+	PromptQuestions:  defaultQuestionPrompt,
+	PromptRegenerate: defaultRegeneratePrompt,
+}
+
+// promptFileKeys are the config keys setting where each template is read from
+var promptFileKeys = map[string]string{
+	PromptQuestions:  "generate.prompt_file",
+	PromptRegenerate: "generate.regenerate_prompt_file",
+}
+
+// DefaultPrompt returns the prompt template shipped with the CLI
+func DefaultPrompt(name string) string {
+	return defaultPrompts[name]
+}
+
+// maxAvoidedQuestions caps how many existing questions are listed in the prompt
+const maxAvoidedQuestions = 200
+
+// Difficulties are the values of the difficulty of generated questions
+var Difficulties = []string{"easy", "medium", "hard"}
+
+const DefaultDifficulty = "medium"
+
+// DefaultMaxTokens is the length of the generated answer, the most Claude 3.5 Sonnet allows
+const DefaultMaxTokens = 8192
+
+// PromptData is what the prompt templates are executed with
+type PromptData struct {
+	CourseTitle string
+	// Chapter is the title of the chapter the lessons are from
+	Chapter string
+	Lessons []PromptLesson
+	// Count is the number of questions to generate
+	Count      int
+	Difficulty string
+	// QuestionTypes are the rules of each question type to generate
+	QuestionTypes []string
+	// ExistingQuestions are the first lines of the questions not to repeat
+	ExistingQuestions []string
+	// Flagged is the question to replace, in the regenerate prompt only
+	Flagged *FlaggedPrompt
+}
+
+// FlaggedPrompt is a flagged question in the regenerate prompt
+type FlaggedPrompt struct {
+	// Question is the flagged question in the JSON quiz file format
+	Question string
+	Type     string
+	// Instruction tells how to fix a question flagged for its reason
+	Instruction string
+	Note        string
+}
+
+// PromptLesson is a lesson in the prompt, numbered from 1 so the generated
+// questions can tell which lesson they are based on
+type PromptLesson struct {
+	Number  int
+	Title   string
+	Content string
+}
+
+// PromptPath returns the file a prompt template is read from when it exists:
+// the generate.prompt_file (or generate.regenerate_prompt_file) config key, or
+// $XDG_CONFIG_HOME/bootdev/prompts/<name>.tmpl (~/.config/bootdev by default)
+func PromptPath(name string) string {
+	if path := viper.GetString(promptFileKeys[name]); path != "" {
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, rest)
+			}
+		}
+		return path
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "bootdev", "prompts", name+".tmpl")
+}
+
+// PromptText returns the text of a prompt template, from PromptPath when the
+// file exists or the default one, and where it comes from
+func PromptText(name string) (text string, source string, err error) {
+	path := PromptPath(name)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultPrompts[name], "", nil
+	}
+	if err != nil {
+		return "", path, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return string(b), path, nil
+}
+
+// ParsePrompt parses a prompt template, and executes it with sample data to
+// catch the fields that don't exist
+func ParsePrompt(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	sample := PromptData{
+		CourseTitle:       "Course",
+		Chapter:           "Chapter",
+		Lessons:           []PromptLesson{{Number: 1, Title: "Lesson", Content: "Content"}},
+		Count:             1,
+		Difficulty:        DefaultDifficulty,
+		QuestionTypes:     []string{questionTypeInstructions[DefaultQuestionTypes[0]]},
+		ExistingQuestions: []string{"Question?"},
+		Flagged: &FlaggedPrompt{
+			Question:    `{"type": "multiple_choice", "question": "Question?"}`,
+			Type:        DefaultQuestionTypes[0],
+			Instruction: flagInstructions["wrong"],
+			Note:        "Note",
+		},
+	}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// loadPrompt reads and parses a prompt template
+func loadPrompt(name string) (*template.Template, error) {
+	text, source, err := PromptText(name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := ParsePrompt(name, text)
+	if err != nil && source != "" {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return tmpl, err
+}
+
+// renderPrompt executes a prompt template, leaving out the oldest
+// existing questions when there are more than maxAvoidedQuestions
+func renderPrompt(tmpl *template.Template, data PromptData) (string, error) {
+	// The most recent questions are the most likely to be repeated
+	if len(data.ExistingQuestions) > maxAvoidedQuestions {
+		data.ExistingQuestions = data.ExistingQuestions[len(data.ExistingQuestions)-maxAvoidedQuestions:]
+	}
+	existing := make([]string, len(data.ExistingQuestions))
+	for i, q := range data.ExistingQuestions {
+		existing[i], _, _ = strings.Cut(q, "\n")
+	}
+	data.ExistingQuestions = existing
+
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return prompt.String(), nil
+}
+
+func generationMaxTokens() int64 {
+	if tokens := viper.GetInt64("llm.max_tokens"); tokens > 0 {
+		return tokens
+	}
+	return DefaultMaxTokens
+}
+
+// generationTemperature returns the llm.temperature config value, or nil to
+// leave the default of the provider
+func generationTemperature() *float64 {
+	if !viper.IsSet("llm.temperature") {
+		return nil
+	}
+	temperature := viper.GetFloat64("llm.temperature")
+	return &temperature
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDefaultPromptsParse(t *testing.T) {
+	for _, name := range PromptNames {
+		if _, err := ParsePrompt(name, DefaultPrompt(name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := ParsePrompt(PromptRegenerate, "{{.Flagged.Reason}}"); err == nil || !strings.Contains(err.Error(), "Reason") {
+		t.Errorf("error = %v, want the unknown field", err)
+	}
+}
+
+func TestPromptText(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	custom := filepath.Join(dir, "custom.tmpl")
+	viper.Set("generate.regenerate_prompt_file", custom)
+	t.Cleanup(func() { viper.Set("generate.regenerate_prompt_file", "") })
+
+	if path := PromptPath(PromptQuestions); path != filepath.Join(dir, "bootdev", "prompts", "questions.tmpl") {
+		t.Errorf("questions path = %s", path)
+	}
+	if text, source, err := PromptText(PromptRegenerate); err != nil || source != "" || text != DefaultPrompt(PromptRegenerate) {
+		t.Errorf("got %q from %q, %v; want the built-in template", text, source, err)
+	}
+	if err := os.WriteFile(custom, []byte("Replace {{.Flagged.Question}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if text, source, err := PromptText(PromptRegenerate); err != nil || source != custom || text != "Replace {{.Flagged.Question}}" {
+		t.Errorf("got %q from %q, %v; want the file", text, source, err)
+	}
+}
@@ -1,146 +1,272 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"slices"
 	"strings"
+	"sync"
+	"text/template"
 
-	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/anthropics/anthropic-sdk-go/option"
 	dao "github.com/bootdotdev/bootdev/db"
 )
 
-// GeneratedQuestion represents a question generated by Claude
+// GeneratedQuestion represents a question generated by the language model
 type GeneratedQuestion struct {
+	Type        string   `json:"type"`
 	Question    string   `json:"question"`
 	Choices     []string `json:"choices"`
 	Answer      string   `json:"answer"`
+	Answers     []string `json:"answers,omitempty"`
 	Explanation string   `json:"explanation"`
+	Rubric      string   `json:"rubric,omitempty"`
+	// Lesson is the number of the lesson the question is based on
+	Lesson int `json:"lesson"`
+
+	// runID is the generation run the question comes from
+	runID int64
 }
 
-// GenerateQuiz generates a quiz using Claude API based on course content
-func GenerateQuiz(c *Course, questionsNumber int) (*dao.Quiz, error) {
-	// Concatenate all lesson content
-	var contentBuilder strings.Builder
-	lessons := c.GetLessons()
-
-	for i, lesson := range lessons {
-		if lesson.Content != "" {
-			contentBuilder.WriteString(fmt.Sprintf("Lesson %d: %s\n", i+1, lesson.Title))
-			contentBuilder.WriteString(lesson.Content)
-			contentBuilder.WriteString("\n\n")
-		}
-	}
+// DefaultQuestionTypes are generated when no question types are requested
+var DefaultQuestionTypes = []string{"multiple_choice"}
 
-	courseContent := contentBuilder.String()
-	if courseContent == "" {
-		return nil, fmt.Errorf("no content available in course lessons")
-	}
+// questionTypeInstructions tells the language model how to fill in each supported question type
+var questionTypeInstructions = map[string]string{
+	"multiple_choice": `"multiple_choice": exactly 4 answer choices in "choices" with one clearly correct answer; "answer" must be copied verbatim from "choices"`,
+	"multi_select":    `"multi_select": a "choose all that apply" question with 4 to 6 answer choices in "choices", of which at least two are correct; "answers" lists every correct choice copied verbatim from "choices" and "answer" is left empty`,
+	"ordering":        `"ordering": a question asking to put 3 to 6 steps of a process in the right sequence; "choices" lists the steps in the correct order and "answer" is left empty`,
+	"free_response":   `"free_response": an open question asking to explain a concept in your own words; "choices" is an empty array, "answer" is a concise reference answer and "rubric" lists the key points a good answer must cover`,
+}
 
-	// Generate questions using Claude API
-	questions, err := generateQuestionsWithClaude(courseContent, questionsNumber)
+// GenerateQuiz generates a quiz with the configured QuestionGenerator based on
+// course content, asking for questions that differ from the existing ones. The
+// course is split into chunks of chapters or lessons that fit in a prompt,
+// generated a few at a time, and the questions are spread over the chunks in
+// proportion to their content so every chapter is covered. Invalid questions
+// are left out and asked again; the report tells what was accepted and
+// rejected, and the quiz may have fewer questions than requested.
+func GenerateQuiz(c *Course, questionsNumber int, questionTypes []string, difficulty string, existing []dao.Question) (*dao.Quiz, GenerationReport, error) {
+	plan, err := newGenerationPlan(c, questionsNumber, questionTypes, difficulty)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate questions: %w", err)
+		return nil, GenerationReport{}, err
 	}
 
+	// Generate the chunks, a few at a time
+	results := make([][]dao.Question, len(plan.chunks))
+	reports := make([]GenerationReport, len(plan.chunks))
+	semaphore := make(chan struct{}, generateConcurrency())
+	var wg sync.WaitGroup
+	for i := range plan.chunks {
+		if plan.chunks[i].questions == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i], reports[i] = plan.generateChunk(c, &plan.chunks[i], existing)
+		}(i)
+	}
+	wg.Wait()
+
 	// Convert to dao.Quiz format
-	quiz := &dao.Quiz{
-		CourseUUID: c.UUID,
-		Questions:  make([]dao.Question, len(questions)),
+	quiz := &dao.Quiz{CourseUUID: c.UUID}
+	var report GenerationReport
+	for i, questions := range results {
+		report.add(reports[i])
+		for _, question := range questions {
+			question.ID = int64(len(quiz.Questions) + 1) // Temporary ID, set when saved to DB
+			quiz.Questions = append(quiz.Questions, question)
+		}
 	}
+	if len(quiz.Questions) == 0 && questionsNumber > 0 {
+		if err := errors.Join(report.Errors...); err != nil {
+			return nil, report, fmt.Errorf("failed to generate questions: %w", err)
+		}
+		return nil, report, fmt.Errorf("no valid questions generated, %d rejected", len(report.Rejected))
+	}
+	return quiz, report, nil
+}
 
-	for i, q := range questions {
-		choicesJSON, err := json.Marshal(q.Choices)
+// ChunkPrompt is the first prompt sent for a chunk of the course
+type ChunkPrompt struct {
+	Chunk     string
+	Questions int
+	Tokens    int
+	Prompt    string
+}
+
+// QuestionPrompts renders the prompts GenerateQuiz would send first, without
+// sending them
+func QuestionPrompts(c *Course, questionsNumber int, questionTypes []string, difficulty string, existing []dao.Question) ([]ChunkPrompt, error) {
+	plan, err := newGenerationPlan(c, questionsNumber, questionTypes, difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := []ChunkPrompt{}
+	for i := range plan.chunks {
+		chunk := &plan.chunks[i]
+		if chunk.questions == 0 {
+			continue
+		}
+		data := plan.promptData(c, chunk, existing)
+		data.Count = chunk.questions
+		prompt, err := renderPrompt(plan.tmpl, data)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal choices: %w", err)
+			return nil, err
 		}
+		prompts = append(prompts, ChunkPrompt{
+			Chunk:     chunk.String(),
+			Questions: chunk.questions,
+			Tokens:    estimateTokens(prompt),
+			Prompt:    prompt,
+		})
+	}
+	return prompts, nil
+}
+
+// generationPlan is how the questions of a course are generated: the prompt
+// template, and the chunks of the course with the questions allocated to them
+type generationPlan struct {
+	tmpl          *template.Template
+	chunks        []contentChunk
+	budget        int
+	questionTypes []string
+	difficulty    string
+}
 
-		quiz.Questions[i] = dao.Question{
-			ID:            int64(i + 1), // Temporary ID
-			QuizID:        0,            // Will be set when saved to DB
-			QuestionType:  "multiple_choice",
-			QuestionText:  q.Question,
-			Explanation:   q.Explanation,
-			AnswerChoices: string(choicesJSON),
-			CorrectAnswer: q.Answer,
+func newGenerationPlan(c *Course, questionsNumber int, questionTypes []string, difficulty string) (*generationPlan, error) {
+	if questionsNumber < 0 {
+		return nil, fmt.Errorf("invalid number of questions %d", questionsNumber)
+	}
+	if len(questionTypes) == 0 {
+		questionTypes = DefaultQuestionTypes
+	}
+	for _, t := range questionTypes {
+		if _, ok := questionTypeInstructions[t]; !ok {
+			return nil, fmt.Errorf("question type %q can't be generated", t)
 		}
 	}
+	if difficulty == "" {
+		difficulty = DefaultDifficulty
+	}
+	if !slices.Contains(Difficulties, difficulty) {
+		return nil, fmt.Errorf("unknown difficulty %q (expected one of %s)", difficulty, strings.Join(Difficulties, ", "))
+	}
 
-	return quiz, nil
+	tmpl, err := loadPrompt(PromptQuestions)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := chunkTokens()
+	chunks := chunkCourse(c, budget)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no content available in course lessons")
+	}
+	allocateQuestions(chunks, questionsNumber)
+
+	return &generationPlan{
+		tmpl:          tmpl,
+		chunks:        chunks,
+		budget:        budget,
+		questionTypes: questionTypes,
+		difficulty:    difficulty,
+	}, nil
 }
 
-// generateQuestionsWithClaude calls Claude API to generate quiz questions
-func generateQuestionsWithClaude(content string, numQuestions int) ([]GeneratedQuestion, error) {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable not set")
-	}
-
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
-
-	prompt := fmt.Sprintf(`Based on the following course content, generate exactly %d multiple choice questions. Each question should:
-
-1. Test understanding of key concepts from the content
-2. Have exactly 4 answer choices (A, B, C, D)  
-3. Have one clearly correct answer
-4. Include a brief explanation of why the answer is correct
-5. DO NOT reuse or duplicate any questions that are already present in the lesson content
-6. Create entirely NEW questions that test the same concepts in different ways
-7. Avoid copying exact wording or examples from the lesson text
-
-Please respond with a valid JSON array in this exact format:
-[
-  {
-    "question": "What is...",
-    "choices": ["Choice A", "Choice B", "Choice C", "Choice D"],
-    "answer": "Choice A",
-    "explanation": "This is correct because..."
-  }
-]
-
-Course Content:
-%s
-
-IMPORTANT: Generate completely original questions that test understanding of the concepts taught, but do NOT copy or reuse any existing questions from the content above. Respond ONLY with the JSON array, no additional text.`, numQuestions, content)
-
-	resp, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaude3_5Sonnet20241022,
-		MaxTokens: 8192, // Maximum tokens for Claude 3.5 Sonnet
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Claude API: %w", err)
+// promptData fills in the prompt template for a chunk, except the number of
+// questions which changes with the follow-up calls
+func (p *generationPlan) promptData(c *Course, chunk *contentChunk, existing []dao.Question) PromptData {
+	data := PromptData{
+		CourseTitle: c.Title,
+		Chapter:     chunk.chapter,
+		Lessons:     chunk.promptLessons(p.budget),
+		Difficulty:  p.difficulty,
+	}
+	for _, t := range p.questionTypes {
+		data.QuestionTypes = append(data.QuestionTypes, questionTypeInstructions[t])
 	}
+	for _, q := range chunk.existingQuestions(existing) {
+		data.ExistingQuestions = append(data.ExistingQuestions, q.QuestionText)
+	}
+	return data
+}
 
-	if len(resp.Content) == 0 {
-		return nil, fmt.Errorf("no content in Claude response")
+// generateChunk generates the questions allocated to a chunk, linking each
+// one to the lesson it is based on
+func (p *generationPlan) generateChunk(c *Course, chunk *contentChunk, existing []dao.Question) ([]dao.Question, GenerationReport) {
+	call := llmCall{kind: "generate", courseUUID: c.UUID, lessons: chunk.lessonRefs(c)}
+	generated, report := generateQuestions(call, p.tmpl, p.promptData(c, chunk, existing), chunk.questions, p.questionTypes)
+	for i := range report.Rejected {
+		report.Rejected[i].Chunk = chunk.String()
+	}
+	for i, err := range report.Errors {
+		report.Errors[i] = fmt.Errorf("%s: %w", chunk, err)
 	}
 
-	// Extract text from the response
-	var responseText string
-	for _, content := range resp.Content {
-		if textBlock := content.AsText(); textBlock.Text != "" {
-			responseText += textBlock.Text
-		}
+	questions := make([]dao.Question, len(generated))
+	for i, q := range generated {
+		// Validated by generateQuestions
+		questions[i], _ = q.toSourcedQuestion(p.questionTypes[0], call.lessons)
+	}
+	return questions, report
+}
+
+// toSourcedQuestion converts a generated question to the way questions are
+// stored, linked to the lesson it is based on and to its generation run
+func (q GeneratedQuestion) toSourcedQuestion(defaultType string, lessons []dao.LessonRef) (dao.Question, error) {
+	question, err := q.toQuestion(defaultType)
+	if err != nil {
+		return dao.Question{}, err
+	}
+	if q.Lesson >= 1 && q.Lesson <= len(lessons) {
+		lesson := lessons[q.Lesson-1]
+		question.LessonUUID, question.LessonSlug = lesson.LessonUUID, lesson.LessonSlug
+		question.ChapterUUID, question.ChapterSlug = lesson.ChapterUUID, lesson.ChapterSlug
 	}
+	question.GenerationRunID = q.runID
+	return question, nil
+}
 
-	if responseText == "" {
-		return nil, fmt.Errorf("no text content in Claude response")
+// toQuestion converts a generated question to the way questions are stored
+func (q GeneratedQuestion) toQuestion(defaultType string) (dao.Question, error) {
+	questionType := q.Type
+	if questionType == "" {
+		questionType = defaultType
 	}
 
-	// Parse the generated questions
-	var questions []GeneratedQuestion
-	if err := json.Unmarshal([]byte(responseText), &questions); err != nil {
-		return nil, fmt.Errorf("failed to parse generated questions: %w", err)
+	choicesJSON := ""
+	if len(q.Choices) > 0 {
+		b, err := json.Marshal(q.Choices)
+		if err != nil {
+			return dao.Question{}, fmt.Errorf("failed to marshal choices: %w", err)
+		}
+		choicesJSON = string(b)
 	}
 
-	if len(questions) != numQuestions {
-		return nil, fmt.Errorf("expected %d questions, got %d", numQuestions, len(questions))
+	correctAnswer := q.Answer
+	if questionType == "ordering" {
+		// The choices come in the correct order, they are shuffled when the quiz runs
+		correctAnswer = choicesJSON
+	}
+	if questionType == "multi_select" {
+		b, err := json.Marshal(q.Answers)
+		if err != nil {
+			return dao.Question{}, fmt.Errorf("failed to marshal answers: %w", err)
+		}
+		correctAnswer = string(b)
 	}
 
-	return questions, nil
+	return dao.Question{
+		QuestionType:  questionType,
+		QuestionText:  q.Question,
+		Explanation:   q.Explanation,
+		AnswerChoices: choicesJSON,
+		CorrectAnswer: correctAnswer,
+		Rubric:        q.Rubric,
+	}, nil
 }
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	dao "github.com/bootdotdev/bootdev/db"
+)
+
+// flagInstructions tells the language model how to fix a question flagged for each reason
+var flagInstructions = map[string]string{
+	"wrong":     "The flagged question or its answer is wrong: the new question must be factually correct and its answer must follow from the lesson.",
+	"ambiguous": "The flagged question is ambiguous: the new question must have exactly one defensible answer, with wrong choices that are clearly wrong to someone who understood the lesson.",
+	"too_easy":  "The flagged question is too easy: the new question must require real understanding of the concept, not recognizing a word from the lesson.",
+}
+
+// RegenerateQuestion asks the language model for a question replacing a flagged
+// one, based on the content of the lesson it was generated from, or of the whole
+// course when the lesson is not known. The new question keeps the type and the
+// lesson of the flagged one.
+func RegenerateQuestion(q *dao.Question) (dao.Question, error) {
+	tmpl, lessons, err := regenerateSource(q)
+	if err != nil {
+		return dao.Question{}, err
+	}
+	return regenerateQuestion(tmpl, q, lessons)
+}
+
+// RegeneratePrompt renders the prompt RegenerateQuestion would send first,
+// without sending it
+func RegeneratePrompt(q *dao.Question) (string, error) {
+	tmpl, lessons, err := regenerateSource(q)
+	if err != nil {
+		return "", err
+	}
+	data, _, err := regeneratePromptData(q, lessons)
+	if err != nil {
+		return "", err
+	}
+	data.Count = 1
+	return renderPrompt(tmpl, data)
+}
+
+// regenerateSource loads the regenerate prompt template and the lessons a
+// flagged question is based on
+func regenerateSource(q *dao.Question) (*template.Template, []PromptLesson, error) {
+	tmpl, err := loadPrompt(PromptRegenerate)
+	if err != nil {
+		return nil, nil, err
+	}
+	lessons, err := sourceLessons(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tmpl, lessons, nil
+}
+
+// regenerateQuestion asks for the question replacing a flagged one the way
+// questions are generated, validating it and asking again when it is invalid
+func regenerateQuestion(tmpl *template.Template, q *dao.Question, lessons []PromptLesson) (dao.Question, error) {
+	data, questionType, err := regeneratePromptData(q, lessons)
+	if err != nil {
+		return dao.Question{}, err
+	}
+
+	call := llmCall{kind: "regenerate", courseUUID: q.CourseUUID}
+	generated, report := generateQuestions(call, tmpl, data, 1, []string{questionType})
+	if len(generated) == 0 {
+		if err := errors.Join(report.Errors...); err != nil {
+			return dao.Question{}, fmt.Errorf("failed to regenerate question: %w", err)
+		}
+		reasons := make([]string, len(report.Rejected))
+		for i, r := range report.Rejected {
+			reasons[i] = r.Reason
+		}
+		return dao.Question{}, fmt.Errorf("no valid question regenerated: %s", strings.Join(reasons, "; "))
+	}
+
+	question, err := generated[0].toQuestion(questionType)
+	if err != nil {
+		return dao.Question{}, err
+	}
+	question.ID = q.ID
+	question.QuizID = q.QuizID
+	question.CourseUUID = q.CourseUUID
+	question.LessonUUID, question.LessonSlug = q.LessonUUID, q.LessonSlug
+	question.ChapterUUID, question.ChapterSlug = q.ChapterUUID, q.ChapterSlug
+	question.GenerationRunID = generated[0].runID
+	return question, nil
+}
+
+// regeneratePromptData fills in the regenerate prompt template for a flagged
+// question, which is replaced by a question of the same type
+func regeneratePromptData(q *dao.Question, lessons []PromptLesson) (PromptData, string, error) {
+	questionType := q.QuestionType
+	if _, ok := questionTypeInstructions[questionType]; !ok {
+		questionType = DefaultQuestionTypes[0]
+	}
+
+	flagged, err := json.MarshalIndent(q.ToFile(), "", "  ")
+	if err != nil {
+		return PromptData{}, "", err
+	}
+	return PromptData{
+		Lessons:       lessons,
+		QuestionTypes: []string{questionTypeInstructions[questionType]},
+		Flagged: &FlaggedPrompt{
+			Question:    string(flagged),
+			Type:        questionType,
+			Instruction: flagInstructions[q.FlagReason],
+			Note:        q.FlagNote,
+		},
+	}, questionType, nil
+}
+
+// sourceLessons returns the lesson a question was generated from, or every
+// lesson of its course when the lesson is not known
+func sourceLessons(q *dao.Question) ([]PromptLesson, error) {
+	if q.LessonUUID != "" {
+		lesson, err := LoadLesson(q.LessonUUID)
+		if err != nil {
+			return nil, err
+		}
+		return []PromptLesson{{Number: 1, Title: lesson.Title, Content: lesson.Content}}, nil
+	}
+
+	course, err := LoadCourse(q.CourseUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load course %s: %w", q.CourseUUID, err)
+	}
+	var lessons []PromptLesson
+	for i, lesson := range course.GetLessons() {
+		if lesson.Content != "" {
+			lessons = append(lessons, PromptLesson{Number: i + 1, Title: lesson.Title, Content: lesson.Content})
+		}
+	}
+	if len(lessons) == 0 {
+		return nil, fmt.Errorf("no content available in course lessons")
+	}
+	return lessons, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/viper"
+)
+
+func regenerateTemplate(t *testing.T) *template.Template {
+	t.Helper()
+	tmpl, err := ParsePrompt(PromptRegenerate, DefaultPrompt(PromptRegenerate))
+	if err != nil {
+		t.Fatalf("ParsePrompt: %v", err)
+	}
+	return tmpl
+}
+
+func TestRegenerateQuestion(t *testing.T) {
+	prompts := stubAsk(t,
+		// A type that wasn't asked for, then a valid question in a bare array
+		`{"questions":[{"type":"fill_blank","question":"Q2","answer":"a"}]}`,
+		`[`+multipleChoiceJSON("Q3", "b", "a", "b", "c", "d")+`]`,
+	)
+	flagged := &dao.Question{
+		ID:            7,
+		QuizID:        2,
+		CourseUUID:    "course",
+		LessonUUID:    "lesson",
+		LessonSlug:    "joins",
+		QuestionType:  "multiple_choice",
+		QuestionText:  "Q1",
+		AnswerChoices: `["a","b"]`,
+		CorrectAnswer: "a",
+		FlagReason:    "ambiguous",
+		FlagNote:      "both are right",
+	}
+
+	question, err := regenerateQuestion(regenerateTemplate(t), flagged, []PromptLesson{{Number: 1, Title: "Joins", Content: "JOIN content"}})
+	if err != nil {
+		t.Fatalf("regenerateQuestion: %v", err)
+	}
+
+	if len(*prompts) != 2 {
+		t.Fatalf("made %d calls, want 2", len(*prompts))
+	}
+	for _, want := range []string{`"question": "Q1"`, `note: "both are right"`, flagInstructions["ambiguous"], "Lesson 1: Joins\nJOIN content"} {
+		if !strings.Contains((*prompts)[0], want) {
+			t.Errorf("prompt doesn't mention %q:\n%s", want, (*prompts)[0])
+		}
+	}
+	if question.QuestionText != "Q3" || question.QuestionType != "multiple_choice" || question.CorrectAnswer != "b" {
+		t.Errorf("question = %+v, want the valid multiple_choice Q3", question)
+	}
+	if question.ID != 7 || question.QuizID != 2 || question.LessonUUID != "lesson" || question.LessonSlug != "joins" || question.GenerationRunID != 2 {
+		t.Errorf("question = %+v, want the ID and lesson of the flagged one and run 2", question)
+	}
+}
+
+func TestRegenerateQuestionGivesUp(t *testing.T) {
+	stubAsk(t, "not JSON", `{"questions":[]}`, `{"questions":[`+multipleChoiceJSON("Q2", "e", "a", "b")+`]}`)
+	flagged := &dao.Question{ID: 7, QuestionType: "multiple_choice", QuestionText: "Q1", FlagReason: "wrong"}
+
+	_, err := regenerateQuestion(regenerateTemplate(t), flagged, []PromptLesson{{Number: 1, Title: "Joins", Content: "JOIN content"}})
+	if err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Errorf("error = %v, want the parse error of the first call", err)
+	}
+}
+
+func TestRegenerateQuestionUsesTheModelSettings(t *testing.T) {
+	viper.Set("llm.max_tokens", 1000)
+	viper.Set("llm.temperature", 0.3)
+	t.Cleanup(func() {
+		viper.Set("llm.max_tokens", nil)
+		viper.Set("llm.temperature", nil)
+	})
+	var got CompletionRequest
+	previousAsk := ask
+	ask = func(call llmCall, req CompletionRequest) (string, int64, error) {
+		got = req
+		return `{"questions":[` + multipleChoiceJSON("Q2", "a", "a", "b") + `]}`, 1, nil
+	}
+	t.Cleanup(func() { ask = previousAsk })
+
+	flagged := &dao.Question{ID: 7, QuestionType: "multiple_choice", QuestionText: "Q1", FlagReason: "wrong"}
+	if _, err := regenerateQuestion(regenerateTemplate(t), flagged, nil); err != nil {
+		t.Fatalf("regenerateQuestion: %v", err)
+	}
+	if got.MaxTokens != 1000 || got.Temperature == nil || *got.Temperature != 0.3 {
+		t.Errorf("max tokens %d, temperature %v; want 1000 and 0.3", got.MaxTokens, got.Temperature)
+	}
+	if got.Schema == nil || got.Schema.Name != "submit_questions" {
+		t.Errorf("schema = %+v, want the questions schema", got.Schema)
+	}
+}
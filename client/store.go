@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	dao "github.com/bootdotdev/bootdev/db"
+)
+
+// RefreshReport tells what a download of a course changed in its local copy
+type RefreshReport struct {
+	Lessons int
+	// Downloaded lessons are the new ones, the ones whose listing changed and
+	// the ones not downloaded yet, or all of them when forced
+	Downloaded int
+	// Failed lessons could not be downloaded, their previous content is kept
+	Failed int
+	// Added lessons were not in the local copy
+	Added []string
+	// Changed lessons are the downloaded ones whose content differs from the
+	// local copy
+	Changed []string
+	// Removed lessons are no longer in the course
+	Removed []string
+}
+
+// Skipped returns the number of lessons whose local copy is up to date, or
+// that were not downloaded because the download was canceled
+func (r RefreshReport) Skipped() int {
+	return r.Lessons - r.Downloaded - r.Failed
+}
+
+// LoadCourse returns a course with the content of its lessons from the local
+// copy, downloading it the first time
+func LoadCourse(courseUUID string) (*Course, error) {
+	course, err := LoadLocalCourse(courseUUID)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil {
+		course, _, err := RefreshCourse(context.Background(), courseUUID, false, nil)
+		return course, err
+	}
+	return course, nil
+}
+
+// LoadLocalCourse returns the local copy of a course, or nil when it was never
+// downloaded
+func LoadLocalCourse(courseUUID string) (*Course, error) {
+	stored, err := dao.GetCourse(nil, courseUUID)
+	if err != nil || stored == nil {
+		return nil, err
+	}
+	return fromStoredCourse(stored), nil
+}
+
+// LoadLesson returns a lesson with its content from the local copy, or
+// downloads it when its course was never downloaded
+func LoadLesson(lessonUUID string) (*CourseLesson, error) {
+	stored, err := dao.GetLesson(nil, lessonUUID, "")
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.Content == "" {
+		return FetchLessonContent(lessonUUID)
+	}
+	return &CourseLesson{UUID: stored.UUID, Slug: stored.Slug, Title: stored.Title, Content: stored.Content}, nil
+}
+
+// RefreshCourse downloads the chapters and lessons of a course and updates its
+// local copy. The content of a lesson is only downloaded again when its entry
+// in the course listing changed, or it was never downloaded, unless force is set.
+// The listing doesn't include the readme, so a lesson whose readme was edited
+// without anything else in its entry changing is only downloaded again with force.
+// onProgress, when set, is told how far along the download of the lessons is.
+// When some lessons could not be downloaded, or ctx is canceled, the rest is
+// still saved and the course returned along with a *DownloadError.
+func RefreshCourse(ctx context.Context, courseUUID string, force bool, onProgress func(DownloadProgress)) (*Course, RefreshReport, error) {
+	var report RefreshReport
+	url := fmt.Sprintf("/v1/courses/%s", courseUUID)
+	resp, code, err := fetchWithAuthContext(ctx, "GET", url, nil)
+	if err == nil {
+		err = checkStatus("GET", url, code, resp)
+	}
+	if err != nil {
+		return nil, report, err
+	}
+	var c Course
+	if err := json.Unmarshal(resp, &c); err != nil {
+		return nil, report, err
+	}
+	// The raw lessons of the listing tell which ones changed since the last download
+	var listing struct {
+		Chapters []struct {
+			Lessons []json.RawMessage `json:"Lessons"`
+		} `json:"Chapters"`
+	}
+	if err := json.Unmarshal(resp, &listing); err != nil {
+		return nil, report, err
+	}
+
+	previous := map[string]dao.Lesson{}
+	stored, err := dao.GetCourse(nil, courseUUID)
+	if err != nil {
+		return nil, report, err
+	}
+	if stored != nil {
+		for _, chapter := range stored.Chapters {
+			for _, l := range chapter.Lessons {
+				previous[l.UUID] = l
+			}
+		}
+	}
+
+	listingHashes := map[string]string{}
+	var stale []*CourseLesson
+	for i := range c.Chapters {
+		for j := range c.Chapters[i].Lessons {
+			lesson := &c.Chapters[i].Lessons[j]
+			listingHashes[lesson.UUID] = hashContent(string(listing.Chapters[i].Lessons[j]))
+			report.Lessons++
+
+			old, ok := previous[lesson.UUID]
+			if ok && !force && old.ContentHash != "" && old.ListingHash == listingHashes[lesson.UUID] {
+				lesson.Content = old.Content
+				continue
+			}
+			stale = append(stale, lesson)
+		}
+	}
+	downloadErr := downloadLessonsContent(ctx, stale, report.Lessons-len(stale), onProgress)
+	var failed *DownloadError
+	if errors.As(downloadErr, &failed) {
+		report.Failed = len(failed.Failed)
+	}
+
+	now := time.Now()
+	fetched := map[string]bool{}
+	for _, lesson := range stale {
+		old, ok := previous[lesson.UUID]
+		if lesson.Content == "" {
+			lesson.Content = old.Content
+			continue
+		}
+		report.Downloaded++
+		fetched[lesson.UUID] = true
+		if !ok {
+			report.Added = append(report.Added, lesson.Slug)
+		} else if hashContent(lesson.Content) != old.ContentHash {
+			report.Changed = append(report.Changed, lesson.Slug)
+		}
+	}
+
+	course := &dao.Course{UUID: c.UUID, Slug: c.Slug, Title: c.Title, Description: c.Descriptiopn, FetchedAt: now}
+	if course.UUID == "" {
+		course.UUID = courseUUID
+	}
+	for _, chapter := range c.Chapters {
+		stored := dao.Chapter{UUID: chapter.UUID, Slug: chapter.Slug, Title: chapter.Title}
+		for _, lesson := range chapter.Lessons {
+			old := previous[lesson.UUID]
+			delete(previous, lesson.UUID)
+			l := dao.Lesson{UUID: lesson.UUID, Slug: lesson.Slug, Title: lesson.Title, Content: lesson.Content}
+			if fetched[lesson.UUID] {
+				l.ContentHash, l.ListingHash, l.FetchedAt = hashContent(lesson.Content), listingHashes[lesson.UUID], now
+			} else {
+				// Kept, or failed to download: the old listing hash has it downloaded again next time
+				l.ContentHash, l.ListingHash, l.FetchedAt = old.ContentHash, old.ListingHash, old.FetchedAt
+			}
+			stored.Lessons = append(stored.Lessons, l)
+		}
+		course.Chapters = append(course.Chapters, stored)
+	}
+	for _, old := range previous {
+		report.Removed = append(report.Removed, old.Slug)
+	}
+	sort.Strings(report.Removed)
+
+	if err := dao.SaveCourse(nil, course); err != nil {
+		return nil, report, err
+	}
+	if downloadErr != nil {
+		return &c, report, downloadErr
+	}
+	return &c, report, nil
+}
+
+func fromStoredCourse(stored *dao.Course) *Course {
+	course := &Course{UUID: stored.UUID, Slug: stored.Slug, Title: stored.Title, Descriptiopn: stored.Description}
+	for _, chapter := range stored.Chapters {
+		c := CourseChapter{UUID: chapter.UUID, Slug: chapter.Slug, Title: chapter.Title}
+		for _, l := range chapter.Lessons {
+			c.Lessons = append(c.Lessons, CourseLesson{UUID: l.UUID, Slug: l.Slug, Title: l.Title, Content: l.Content})
+		}
+		course.Chapters = append(course.Chapters, c)
+	}
+	return course
+}
+
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
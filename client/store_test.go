@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// fakeAPI serves the API with handler and keeps the local copy in a temporary database
+func fakeAPI(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	viper.Set("api_url", srv.URL)
+	viper.Set("db_path", filepath.Join(t.TempDir(), "test.db"))
+	t.Cleanup(func() {
+		viper.Set("api_url", nil)
+		viper.Set("db_path", nil)
+	})
+}
+
+// lessonResponse is the body of /v1/static/lessons/<uuid>
+func lessonResponse(readme string) []byte {
+	var resp LessonResp
+	resp.Lesson.LessonDataCodeCompletion.Readme = readme
+	body, _ := json.Marshal(resp)
+	return body
+}
+
+// fakeCourse is a course served by the fake API, its lessons in one chapter
+type fakeCourse struct {
+	mu       sync.Mutex
+	lessons  []CourseLesson
+	readmes  map[string]string
+	requests map[string]int
+}
+
+func (c *fakeCourse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.URL.Path == "/v1/courses/course" {
+		json.NewEncoder(w).Encode(Course{UUID: "course", Slug: "sql", Title: "SQL", Chapters: []CourseChapter{
+			{UUID: "chapter", Slug: "basics", Title: "Basics", Lessons: c.lessons},
+		}})
+		return
+	}
+	uuid := strings.TrimPrefix(r.URL.Path, "/v1/static/lessons/")
+	c.requests[uuid]++
+	readme, ok := c.readmes[uuid]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(lessonResponse(readme))
+}
+
+func (c *fakeCourse) set(readmes map[string]string, slugs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lessons = nil
+	for _, slug := range slugs {
+		c.lessons = append(c.lessons, CourseLesson{UUID: slug, Slug: slug, Title: strings.ToUpper(slug)})
+	}
+	c.readmes = readmes
+	c.requests = map[string]int{}
+}
+
+func TestRefreshCourse(t *testing.T) {
+	course := &fakeCourse{}
+	fakeAPI(t, course.ServeHTTP)
+
+	course.set(map[string]string{"l1": "one", "l2": "two", "l3": "three"}, "l1", "l2", "l3")
+	_, report, err := RefreshCourse(context.Background(), "course", false, nil)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if report.Downloaded != 3 || !reflect.DeepEqual(report.Added, []string{"l1", "l2", "l3"}) {
+		t.Errorf("first refresh = %+v, want the 3 lessons added", report)
+	}
+
+	// l2 is renamed and edited, l3 removed, l4 added and l5 can't be downloaded
+	course.set(map[string]string{"l1": "one", "l2": "two, edited", "l4": "four"}, "l1", "l2", "l4", "l5")
+	course.lessons[1].Title = "Two"
+	c, report, err := RefreshCourse(context.Background(), "course", false, nil)
+	var downloadErr *DownloadError
+	if !errors.As(err, &downloadErr) || len(downloadErr.Failed) != 1 || !strings.Contains(err.Error(), "l5") {
+		t.Fatalf("error = %v, want l5 to fail", err)
+	}
+	want := RefreshReport{Lessons: 4, Downloaded: 2, Failed: 1, Added: []string{"l4"}, Changed: []string{"l2"}, Removed: []string{"l3"}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("second refresh = %+v, want %+v", report, want)
+	}
+	if course.requests["l1"] != 0 {
+		t.Errorf("downloaded l1 again, its listing entry didn't change")
+	}
+	if got := lessonContents(c); got != "l1=one l2=two, edited l4=four l5=" {
+		t.Errorf("lessons = %s", got)
+	}
+
+	// A readme edited without its listing entry changing is only noticed with force
+	course.set(map[string]string{"l1": "one, edited", "l2": "two, edited", "l4": "four", "l5": "five"}, "l1", "l2", "l4", "l5")
+	course.lessons[1].Title = "Two"
+	if _, report, err = RefreshCourse(context.Background(), "course", false, nil); err != nil || report.Downloaded != 1 || !reflect.DeepEqual(report.Changed, []string{"l5"}) {
+		t.Errorf("refresh = %+v, %v; want only the failed l5 downloaded", report, err)
+	}
+	if _, report, err = RefreshCourse(context.Background(), "course", true, nil); err != nil || report.Downloaded != 4 || !reflect.DeepEqual(report.Changed, []string{"l1"}) {
+		t.Errorf("forced refresh = %+v, %v; want l1 changed", report, err)
+	}
+}
+
+func lessonContents(c *Course) string {
+	var contents []string
+	for _, chapter := range c.Chapters {
+		for _, l := range chapter.Lessons {
+			contents = append(contents, fmt.Sprintf("%s=%s", l.Slug, l.Content))
+		}
+	}
+	return strings.Join(contents, " ")
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
+	dao "github.com/bootdotdev/bootdev/db"
 	"github.com/spf13/viper"
 )
 
@@ -54,3 +57,50 @@ func GetUserCourses() ([]UserCourse, error) {
 
 	return tacResp.Data.Courses, nil
 }
+
+// CourseList is the course list of the user, from the API or from the cache
+// when the API can't be reached
+type CourseList struct {
+	Courses []UserCourse
+	// Offline is why the API couldn't be used, nil when the list is fresh
+	Offline error
+	// CachedAt is when the cached list was fetched, when Offline
+	CachedAt time.Time
+}
+
+// LoadUserCourses fetches the course list of the user and caches it, or
+// returns the cached one when the API can't be reached. Failing to cache the
+// list only prints a warning, the fresh list is still returned.
+func LoadUserCourses() (*CourseList, error) {
+	courses, err := GetUserCourses()
+	if err != nil {
+		return CachedUserCourses(err)
+	}
+
+	cached := make([]dao.UserCourse, len(courses))
+	for i, c := range courses {
+		cached[i] = dao.UserCourse{UUID: c.UUID, Slug: c.Slug, Title: c.Title, CompletedAt: c.CompletedAt}
+	}
+	if err := dao.SaveUserCourses(nil, viper.GetString("user_handle"), cached); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache the course list: %v\n", err)
+	}
+	return &CourseList{Courses: courses}, nil
+}
+
+// CachedUserCourses returns the cached course list of the user, without
+// calling the API because of the reason given
+func CachedUserCourses(reason error) (*CourseList, error) {
+	cached, cachedAt, err := dao.GetUserCourses(nil, viper.GetString("user_handle"))
+	if err != nil {
+		return nil, err
+	}
+	if len(cached) == 0 {
+		return nil, fmt.Errorf("%w\nNo course list is cached yet, run bootdev quiz once online, or quiz-mgmt start <COURSE_UUID>", reason)
+	}
+
+	list := &CourseList{Offline: reason, CachedAt: cachedAt}
+	for _, c := range cached {
+		list.Courses = append(list.Courses, UserCourse{UUID: c.UUID, Slug: c.Slug, Title: c.Title, CompletedAt: c.CompletedAt})
+	}
+	return list, nil
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/viper"
+)
+
+func TestLoadUserCourses(t *testing.T) {
+	online := true
+	fakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if !online {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var resp HttpTaCResponse
+		resp.Data.Courses = []UserCourse{{UUID: "c1", Slug: "sql", Title: "SQL", CompletedAt: "2026-01-02"}, {UUID: "c2", Slug: "go", Title: "Go"}}
+		json.NewEncoder(w).Encode(resp)
+	})
+	viper.Set("user_handle", "alice")
+	t.Cleanup(func() { viper.Set("user_handle", nil) })
+
+	// Offline before the list was ever fetched
+	online = false
+	if _, err := LoadUserCourses(); err == nil || !strings.Contains(err.Error(), "No course list is cached yet") {
+		t.Errorf("error = %v, want no course list cached yet", err)
+	}
+
+	online = true
+	fresh, err := LoadUserCourses()
+	if err != nil || fresh.Offline != nil || len(fresh.Courses) != 2 {
+		t.Fatalf("list = %+v, %v; want the 2 courses of the API", fresh, err)
+	}
+
+	online = false
+	cached, err := LoadUserCourses()
+	if err != nil {
+		t.Fatalf("LoadUserCourses offline: %v", err)
+	}
+	if cached.Offline == nil || cached.CachedAt.IsZero() || !reflect.DeepEqual(cached.Courses, fresh.Courses) {
+		t.Errorf("list = %+v, want the cached courses", cached)
+	}
+}
+
+func TestLoadUserCoursesWhenCachingFails(t *testing.T) {
+	fakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		var resp HttpTaCResponse
+		resp.Data.Courses = []UserCourse{{UUID: "c1", Slug: "sql", Title: "SQL"}}
+		json.NewEncoder(w).Encode(resp)
+	})
+	viper.Set("user_handle", "alice")
+	t.Cleanup(func() { viper.Set("user_handle", nil) })
+	db, err := dao.InitializeDatabase(dao.DefaultDBConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`DROP TABLE user_courses`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadUserCourses()
+	if err != nil || len(list.Courses) != 1 {
+		t.Errorf("list = %+v, %v; want the course of the API", list, err)
+	}
+}
@@ -3,7 +3,9 @@ package cmd
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
+	dao "github.com/bootdotdev/bootdev/db"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -143,9 +145,39 @@ var configureBaseURLCmd = &cobra.Command{
 	},
 }
 
+// configureSchedulerCmd represents the `configure scheduler` command for
+// choosing the spaced repetition algorithm used by quizzes
+var configureSchedulerCmd = &cobra.Command{
+	Use:       "scheduler [name]",
+	Short:     "Get or set the spaced repetition scheduler used by quizzes",
+	Args:      cobra.RangeArgs(0, 1),
+	ValidArgs: dao.SchedulerNames(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			fmt.Printf("Scheduler: %s (available: %s)\n", viper.GetString("quiz.scheduler"), strings.Join(dao.SchedulerNames(), ", "))
+			return nil
+		}
+
+		scheduler, err := dao.GetScheduler(args[0])
+		if err != nil {
+			return err
+		}
+
+		viper.Set("quiz.scheduler", scheduler.Name())
+		err = viper.WriteConfig()
+		if err != nil {
+			return fmt.Errorf("failed to write config: %v", err)
+		}
+		fmt.Printf("Scheduler set to %v\n", scheduler.Name())
+		return err
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(configureCmd)
 
+	configureCmd.AddCommand(configureSchedulerCmd)
+
 	configureCmd.AddCommand(configureBaseURLCmd)
 	configureBaseURLCmd.Flags().Bool("reset", false, "reset the base URL to use the lesson's defaults")
 
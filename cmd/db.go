@@ -40,11 +40,17 @@ var dbResetCmd = &cobra.Command{
 	},
 }
 
+var dbRetentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Compare retention across spaced repetition schedulers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showRetentionStats()
+		return nil
+	},
+}
+
 func initDatabase() {
-	config := dao.DBConfig{
-		InitFile: "db/init.sql",
-		DBPath:   "data/bootdev.db",
-	}
+	config := dao.DefaultDBConfig()
 	_, err := dao.InitializeDatabase(config)
 	if err != nil {
 		fmt.Printf("Error initting the DB %v", err)
@@ -54,10 +60,7 @@ func initDatabase() {
 }
 
 func showDatabaseStats() {
-	config := dao.DBConfig{
-		InitFile: "db/init.sql",
-		DBPath:   "data/bootdev.db",
-	}
+	config := dao.DefaultDBConfig()
 	db, err := dao.InitializeDatabase(config)
 	if err != nil {
 		fmt.Printf("Error connecting to database: %v\n", err)
@@ -74,6 +77,7 @@ func showDatabaseStats() {
 	fmt.Println("=== Database Statistics by Quiz ===")
 	for _, stat := range stats {
 		fmt.Printf("\nCourse UUID: %s\n", stat.CourseUUID)
+		fmt.Printf("  Deck: %s\n", stat.Deck)
 		fmt.Printf("  Questions: %d\n", stat.QuestionCount)
 		fmt.Printf("  Total Answers: %d\n", stat.TotalAnswers)
 		fmt.Printf("  Correct Answers: %d\n", stat.CorrectAnswers)
@@ -85,11 +89,38 @@ func showDatabaseStats() {
 	}
 }
 
-func resetDatabase() {
-	config := dao.DBConfig{
-		InitFile: "db/init.sql",
-		DBPath:   "data/bootdev.db",
+func showRetentionStats() {
+	config := dao.DefaultDBConfig()
+	db, err := dao.InitializeDatabase(config)
+	if err != nil {
+		fmt.Printf("Error connecting to database: %v\n", err)
+		return
 	}
+	defer db.Close()
+
+	stats, err := dao.GetRetentionStats(db)
+	if err != nil {
+		fmt.Printf("Error retrieving retention stats: %v\n", err)
+		return
+	}
+
+	fmt.Println("=== Retention by Scheduler ===")
+	if len(stats) == 0 {
+		fmt.Println("\nNo repeat reviews yet")
+		return
+	}
+	for _, stat := range stats {
+		fmt.Printf("\nScheduler: %s\n", stat.Scheduler)
+		fmt.Printf("  Questions Reviewed: %d\n", stat.QuestionsReviewed)
+		fmt.Printf("  Repeat Reviews: %d\n", stat.Reviews)
+		fmt.Printf("  Recalled: %d\n", stat.Recalled)
+		fmt.Printf("  Retention Rate: %.1f%%\n", stat.RetentionRate)
+		fmt.Printf("  Avg Days Between Reviews: %.1f\n", stat.AvgIntervalDays)
+	}
+}
+
+func resetDatabase() {
+	config := dao.DefaultDBConfig()
 	err := dao.ResetDatabase(config)
 	if err != nil {
 		fmt.Printf("Error resetting database: %v\n", err)
@@ -103,4 +134,5 @@ func init() {
 	dbCmd.AddCommand(dbInitCmd)
 	dbCmd.AddCommand(dbStatsCmd)
 	dbCmd.AddCommand(dbResetCmd)
+	dbCmd.AddCommand(dbRetentionCmd)
 }
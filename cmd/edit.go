@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	dao "github.com/bootdotdev/bootdev/db"
+	render "github.com/bootdotdev/bootdev/render"
+	"github.com/spf13/cobra"
+)
+
+var editQuizCmd = &cobra.Command{
+	Use:   "edit <COURSE_UUID>",
+	Short: "Browse and fix the questions of a course",
+	Long: `Opens the question bank editor: search the questions of a course with /, then edit the
+text, choices, answers, explanation or type of a question, archive it to keep it out of
+quizzes and reviews without losing its history, or delete it. Saving a question flagged
+during a quiz clears its flag.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		deck, _ := cmd.Flags().GetString("deck")
+		render.RenderEditor(dao.QuestionFilter{CourseUUID: args[0], Deck: deck})
+		return nil
+	},
+}
+
+func init() {
+	quizCmd.AddCommand(editQuizCmd)
+
+	editQuizCmd.Flags().String("deck", "", "only edit the questions of this deck")
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/cobra"
+)
+
+var exportQuizCmd = &cobra.Command{
+	Use:   "export <COURSE_UUID>",
+	Short: "Export the questions of a course to a JSON, YAML or Anki file",
+	Long: `Exports the questions of a course in the quiz file format described in docs/quiz-format.md,
+or as an Anki deck (.apkg) with the question on the front of each card and the answer and
+explanation on the back. The format is picked from the output file extension unless --format
+is given, and defaults to YAML when writing to stdout.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		withHistory, _ := cmd.Flags().GetBool("history")
+		deck, _ := cmd.Flags().GetString("deck")
+		exportQuiz(args[0], deck, output, format, withHistory)
+		return nil
+	},
+}
+
+var importQuizCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import questions from a JSON, YAML or Anki file",
+	Long: `Imports a quiz file written by export or by hand (use - for stdin). Questions already in
+the database are recognized by their type, text and answer and updated instead of duplicated,
+so a file can be imported again after it changes.
+
+Anki decks (.apkg) are imported from the first two fields of each note: short answers become
+fill-in-the-blank questions, longer ones free response questions. The course is named after
+the deck unless --course is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		withHistory, _ := cmd.Flags().GetBool("history")
+		courseUUID, _ := cmd.Flags().GetString("course")
+		deck, _ := cmd.Flags().GetString("deck")
+		importQuiz(args[0], format, courseUUID, deck, withHistory)
+		return nil
+	},
+}
+
+var exportMarkdownCmd = &cobra.Command{
+	Use:   "export-md <COURSE_UUID>",
+	Short: "Export the questions of a course to a Markdown file",
+	Long: `Exports the questions of a course in the Markdown format described in docs/quiz-format.md:
+a heading per question, checkboxes for the answers and a quote for the explanation.
+Free response questions can't be written in Markdown and are left out.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		deck, _ := cmd.Flags().GetString("deck")
+		exportQuiz(args[0], deck, output, "md", false)
+		return nil
+	},
+}
+
+var importMarkdownCmd = &cobra.Command{
+	Use:   "import-md <file>",
+	Short: "Import questions from a Markdown file",
+	Long: `Imports questions written in the Markdown format described in docs/quiz-format.md (use - for
+stdin). Like import, questions already in the database are updated instead of duplicated.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		courseUUID, _ := cmd.Flags().GetString("course")
+		deck, _ := cmd.Flags().GetString("deck")
+		importQuiz(args[0], "md", courseUUID, deck, false)
+		return nil
+	},
+}
+
+func exportQuiz(courseUUID, deck, output, format string, withHistory bool) {
+	if format == "" {
+		format = dao.QuizFileFormat(output)
+	}
+	if format == "apkg" && output == "" {
+		fmt.Println("Anki decks can't be written to stdout, use --output")
+		return
+	}
+
+	quizFile, err := dao.ExportQuiz(nil, courseUUID, deck, withHistory)
+	if err != nil {
+		fmt.Printf("Error exporting quiz: %v\n", err)
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Printf("Error creating %s: %v\n", output, err)
+			return
+		}
+		defer f.Close()
+		w = f
+	}
+
+	exported := len(quizFile.Questions)
+	if format == "md" {
+		skipped := 0
+		for _, q := range quizFile.Questions {
+			if !slices.Contains(dao.MarkdownQuestionTypes, q.Type) {
+				skipped++
+			}
+		}
+		exported -= skipped
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, "Skipping %d questions Markdown can't express (only %s)\n", skipped, strings.Join(dao.MarkdownQuestionTypes, ", "))
+		}
+	}
+
+	if err := dao.EncodeQuizFile(w, quizFile, format); err != nil {
+		fmt.Printf("Error writing quiz file: %v\n", err)
+		return
+	}
+	if output != "" {
+		fmt.Printf("Exported %d questions to %s\n", exported, output)
+	}
+}
+
+func importQuiz(path, format, courseUUID, deck string, withHistory bool) {
+	if format == "" {
+		format = dao.QuizFileFormat(path)
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("Error opening %s: %v\n", path, err)
+			return
+		}
+		defer f.Close()
+		r = f
+	}
+
+	quizFile, err := dao.DecodeQuizFile(r, format)
+	if err != nil {
+		fmt.Printf("Invalid quiz file:\n%v\n", err)
+		return
+	}
+	if courseUUID != "" {
+		quizFile.CourseUUID = courseUUID
+	}
+	if deck != "" {
+		quizFile.Deck = deck
+	}
+
+	result, err := dao.ImportQuiz(nil, quizFile, withHistory)
+	if err != nil {
+		fmt.Printf("Error importing quiz: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Imported %s (deck %s): %d added, %d updated, %d unchanged\n", quizFile.CourseUUID, quizFile.GetDeck(), result.Added, result.Updated, result.Unchanged)
+	if withHistory {
+		fmt.Printf("Answers added: %d\n", result.AnswersAdded)
+	}
+}
+
+func init() {
+	quizCmd.AddCommand(exportQuizCmd)
+	quizCmd.AddCommand(importQuizCmd)
+	quizCmd.AddCommand(exportMarkdownCmd)
+	quizCmd.AddCommand(importMarkdownCmd)
+
+	exportQuizCmd.Flags().StringP("output", "o", "", "file to write to (default is stdout)")
+	exportQuizCmd.Flags().StringP("format", "f", "", "json, yaml, apkg or md")
+	exportQuizCmd.Flags().Bool("history", false, "include the answer history")
+	exportQuizCmd.Flags().String("deck", dao.DefaultDeck, "deck to export")
+	importQuizCmd.Flags().StringP("format", "f", "", "json, yaml, apkg or md (default is picked from the file extension)")
+	importQuizCmd.Flags().Bool("history", false, "also import the answer history, if the file has one")
+	importQuizCmd.Flags().String("course", "", "course UUID to import the questions into (default is the one in the file)")
+	importQuizCmd.Flags().String("deck", "", "deck to import the questions into (default is the one in the file)")
+	exportMarkdownCmd.Flags().StringP("output", "o", "", "file to write to (default is stdout)")
+	exportMarkdownCmd.Flags().String("deck", dao.DefaultDeck, "deck to export")
+	importMarkdownCmd.Flags().String("course", "", "course UUID to import the questions into (default is the one in the front matter)")
+	importMarkdownCmd.Flags().String("deck", "", "deck to import the questions into (default is the one in the front matter)")
+}
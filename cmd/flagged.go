@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	api "github.com/bootdotdev/bootdev/client"
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/cobra"
+)
+
+var flaggedCmd = &cobra.Command{
+	Use:   "flagged [COURSE_UUID]",
+	Short: "List the questions flagged during quizzes, and fix them",
+	Long: `Lists the questions flagged as wrong, ambiguous or too easy during a quiz, of every course
+or of one course. Flagged questions are left out of quizzes and reviews until they are
+edited with quiz-mgmt edit, regenerated by the language model from their source lesson
+with --regenerate, put back as they are with --unflag, or deleted with --delete. Use --id
+to only handle some of them.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := dao.QuestionFilter{Flagged: true, IncludeArchived: true}
+		if len(args) > 0 {
+			filter.CourseUUID = args[0]
+		}
+		filter.Deck, _ = cmd.Flags().GetString("deck")
+		ids, _ := cmd.Flags().GetInt64Slice("id")
+		regenerate, _ := cmd.Flags().GetBool("regenerate")
+		unflag, _ := cmd.Flags().GetBool("unflag")
+		remove, _ := cmd.Flags().GetBool("delete")
+
+		switch {
+		case regenerate:
+			handleFlagged(filter, ids, "Regenerated", regenerateQuestion)
+		case unflag:
+			handleFlagged(filter, ids, "Unflagged", func(q dao.Question) error { return dao.UnflagQuestion(nil, q.ID) })
+		case remove:
+			handleFlagged(filter, ids, "Deleted", func(q dao.Question) error { return dao.DeleteQuestion(nil, q.ID) })
+		default:
+			listFlagged(filter, ids)
+		}
+		return nil
+	},
+}
+
+func getFlagged(filter dao.QuestionFilter, ids []int64) ([]dao.Question, error) {
+	questions, err := dao.GetQuestions(nil, filter)
+	if err != nil || len(ids) == 0 {
+		return questions, err
+	}
+	return slices.DeleteFunc(questions, func(q dao.Question) bool {
+		return !slices.Contains(ids, q.ID)
+	}), nil
+}
+
+func listFlagged(filter dao.QuestionFilter, ids []int64) {
+	questions, err := getFlagged(filter, ids)
+	if err != nil {
+		fmt.Printf("Error getting flagged questions: %v\n", err)
+		return
+	}
+	if len(questions) == 0 {
+		fmt.Println("No flagged questions")
+		return
+	}
+
+	for _, q := range questions {
+		fmt.Printf("#%d %s (%s, %s)\n", q.ID, q.FlagReason, q.CourseUUID, q.QuestionType)
+		fmt.Printf("  %s\n", q.QuestionText)
+		if q.FlagNote != "" {
+			fmt.Printf("  Note: %s\n", q.FlagNote)
+		}
+		if q.LessonSlug != "" {
+			fmt.Printf("  Lesson: %s\n", q.LessonSlug)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d flagged questions. Fix them with quiz-mgmt edit, or use --regenerate, --unflag or --delete\n", len(questions))
+}
+
+// handleFlagged applies an action to every flagged question, going on after errors
+func handleFlagged(filter dao.QuestionFilter, ids []int64, done string, action func(dao.Question) error) {
+	questions, err := getFlagged(filter, ids)
+	if err != nil {
+		fmt.Printf("Error getting flagged questions: %v\n", err)
+		return
+	}
+	if len(questions) == 0 {
+		fmt.Println("No flagged questions")
+		return
+	}
+
+	handled := 0
+	for _, q := range questions {
+		if err := action(q); err != nil {
+			fmt.Printf("#%d: %v\n", q.ID, err)
+			continue
+		}
+		fmt.Printf("%s #%d\n", done, q.ID)
+		handled++
+	}
+	fmt.Printf("%s %d of %d flagged questions\n", done, handled, len(questions))
+}
+
+func regenerateQuestion(q dao.Question) error {
+	fmt.Printf("Regenerating #%d: %s\n", q.ID, q.QuestionText)
+	question, err := api.RegenerateQuestion(&q)
+	if err != nil {
+		return err
+	}
+	if err := dao.ReplaceQuestion(nil, q.ID, question.ToFile()); err != nil {
+		return fmt.Errorf("invalid question %q: %w", question.QuestionText, err)
+	}
+	if err := dao.SetGenerationRun(nil, q.ID, question.GenerationRunID); err != nil {
+		return err
+	}
+	fmt.Printf("  New question: %s\n", question.QuestionText)
+	return nil
+}
+
+func init() {
+	quizCmd.AddCommand(flaggedCmd)
+
+	flaggedCmd.Flags().String("deck", "", "only the questions of this deck")
+	flaggedCmd.Flags().Int64Slice("id", nil, "only the questions with these IDs")
+	flaggedCmd.Flags().Bool("regenerate", false, "ask the language model to replace the flagged questions using their source lesson")
+	flaggedCmd.Flags().Bool("unflag", false, "put the flagged questions back as they are")
+	flaggedCmd.Flags().Bool("delete", false, "delete the flagged questions")
+	flaggedCmd.MarkFlagsMutuallyExclusive("regenerate", "unflag", "delete")
+}
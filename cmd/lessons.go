@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/cobra"
+)
+
+// searchContext is how many characters are shown around a search match
+const searchContext = 60
+
+var lessonsCmd = &cobra.Command{
+	Use:   "lessons [COURSE_UUID]",
+	Short: "List the downloaded courses, or the chapters and lessons of one",
+	Long:  `Lists the courses downloaded with quiz-mgmt download_course or by a generation, or the chapters and lessons of one of them with the size of their content and when it was downloaded.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			listCourses()
+		} else {
+			listLessons(args[0])
+		}
+		return nil
+	},
+}
+
+var readLessonCmd = &cobra.Command{
+	Use:   "read <LESSON>",
+	Short: "Print the content of a downloaded lesson",
+	Long:  `Prints the content of a lesson, by UUID or slug, from the local copy of its course.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		courseUUID, _ := cmd.Flags().GetString("course")
+		readLesson(args[0], courseUUID)
+		return nil
+	},
+}
+
+var searchLessonsCmd = &cobra.Command{
+	Use:   "search <QUERY>",
+	Short: "Search the downloaded lessons",
+	Long:  `Lists the downloaded lessons whose title or content contains the query, ignoring case, with the first match in their content.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		courseUUID, _ := cmd.Flags().GetString("course")
+		searchLessons(strings.Join(args, " "), courseUUID)
+		return nil
+	},
+}
+
+func listCourses() {
+	courses, err := dao.GetCourses(nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if len(courses) == 0 {
+		fmt.Println("No course downloaded yet, use quiz-mgmt download_course")
+		return
+	}
+	for _, c := range courses {
+		fmt.Printf("%s  %s (downloaded %s)\n", c.UUID, c.Title, c.FetchedAt.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+func listLessons(courseUUID string) {
+	course, err := dao.GetCourse(nil, courseUUID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if course == nil {
+		fmt.Printf("Course %s is not downloaded, use quiz-mgmt download_course\n", courseUUID)
+		return
+	}
+
+	fmt.Printf("%s: %d lessons, downloaded %s\n", course.Title, course.LessonCount(), course.FetchedAt.Local().Format("2006-01-02 15:04"))
+	for i, chapter := range course.Chapters {
+		fmt.Printf("\n%d. %s (%s)\n", i+1, chapter.Title, chapter.Slug)
+		for j, l := range chapter.Lessons {
+			fetched := "not downloaded"
+			if !l.FetchedAt.IsZero() {
+				fetched = fmt.Sprintf("%6d chars, %s", len(l.Content), l.FetchedAt.Local().Format("2006-01-02"))
+			}
+			fmt.Printf("  %3d. %-50s %s\n", j+1, l.Slug, fetched)
+		}
+	}
+}
+
+func readLesson(ref, courseUUID string) {
+	lesson, err := dao.GetLesson(nil, ref, courseUUID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if lesson == nil {
+		fmt.Printf("Lesson %s is not downloaded, use quiz-mgmt download_course\n", ref)
+		return
+	}
+	if lesson.Content == "" {
+		fmt.Printf("The content of %s could not be downloaded, try quiz-mgmt download_course %s\n", lesson.Slug, lesson.CourseUUID)
+		return
+	}
+	fmt.Printf("%s > %s > %s\n\n", lesson.CourseTitle, lesson.ChapterTitle, lesson.Title)
+	fmt.Println(lesson.Content)
+}
+
+func searchLessons(query, courseUUID string) {
+	lessons, err := dao.SearchLessons(nil, query, courseUUID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if len(lessons) == 0 {
+		fmt.Printf("No downloaded lesson mentions %q\n", query)
+		return
+	}
+	for _, l := range lessons {
+		fmt.Printf("%s > %s > %s (%s)\n", l.CourseTitle, l.ChapterSlug, l.Title, l.Slug)
+		if excerpt := searchExcerpt(l.Content, query); excerpt != "" {
+			fmt.Printf("  %s\n", excerpt)
+		}
+	}
+	fmt.Printf("\n%d lessons found, print one with quiz-mgmt read <LESSON>\n", len(lessons))
+}
+
+// searchExcerpt returns the first match of the query in the content, on one
+// line with some context around it
+func searchExcerpt(content, query string) string {
+	i := strings.Index(strings.ToLower(content), strings.ToLower(query))
+	if i < 0 {
+		return ""
+	}
+	start, end := max(i-searchContext, 0), min(i+len(query)+searchContext, len(content))
+	// Don't cut a character in half
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+	excerpt := strings.Join(strings.Fields(content[start:end]), " ")
+	if start > 0 {
+		excerpt = "..." + excerpt
+	}
+	if end < len(content) {
+		excerpt += "..."
+	}
+	return excerpt
+}
+
+func init() {
+	quizCmd.AddCommand(lessonsCmd)
+	quizCmd.AddCommand(readLessonCmd)
+	quizCmd.AddCommand(searchLessonsCmd)
+
+	readLessonCmd.Flags().String("course", "", "course of the lesson, when its slug is in several courses")
+	searchLessonsCmd.Flags().String("course", "", "only search the lessons of this course")
+}
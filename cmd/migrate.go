@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/cobra"
+)
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Database schema migration commands",
+	Long:  `Commands for inspecting and applying the schema migrations embedded in the CLI. Pending migrations are also applied automatically whenever the database is opened.`,
+}
+
+var dbMigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showMigrationStatus()
+		return nil
+	},
+}
+
+var dbMigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, _ := cmd.Flags().GetInt("to")
+		migrateUp(target)
+		return nil
+	},
+}
+
+var dbMigrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recently applied migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+		migrateDown(steps)
+		return nil
+	},
+}
+
+func showMigrationStatus() {
+	config := dao.DefaultDBConfig()
+	db, err := dao.OpenDatabase(config)
+	if err != nil {
+		fmt.Printf("Error connecting to database: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	statuses, err := dao.GetMigrationStatus(db)
+	if err != nil {
+		fmt.Printf("Error retrieving migration status: %v\n", err)
+		return
+	}
+
+	fmt.Println("=== Schema Migrations ===")
+	for _, status := range statuses {
+		if status.Applied {
+			fmt.Printf("  [x] %04d_%s (applied %s)\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04"))
+		} else {
+			fmt.Printf("  [ ] %04d_%s (pending)\n", status.Version, status.Name)
+		}
+	}
+}
+
+func migrateUp(target int) {
+	config := dao.DefaultDBConfig()
+	db, err := dao.OpenDatabase(config)
+	if err != nil {
+		fmt.Printf("Error connecting to database: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	applied, err := dao.MigrateUp(db, target)
+	for _, m := range applied {
+		fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Printf("Error applying migrations: %v\n", err)
+		return
+	}
+	if len(applied) == 0 {
+		fmt.Println("Database schema is up to date")
+	}
+}
+
+func migrateDown(steps int) {
+	config := dao.DefaultDBConfig()
+	db, err := dao.OpenDatabase(config)
+	if err != nil {
+		fmt.Printf("Error connecting to database: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	reverted, err := dao.MigrateDown(db, steps)
+	for _, m := range reverted {
+		fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Printf("Error reverting migrations: %v\n", err)
+		return
+	}
+	if len(reverted) == 0 {
+		fmt.Println("No migrations to revert")
+	}
+}
+
+func init() {
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbMigrateCmd.AddCommand(dbMigrateStatusCmd)
+	dbMigrateCmd.AddCommand(dbMigrateUpCmd)
+	dbMigrateCmd.AddCommand(dbMigrateDownCmd)
+
+	dbMigrateUpCmd.Flags().Int("to", 0, "Migrate up to this version (default: latest)")
+	dbMigrateDownCmd.Flags().Int("steps", 1, "Number of migrations to revert")
+}
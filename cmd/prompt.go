@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	api "github.com/bootdotdev/bootdev/client"
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Show, edit and try the prompts questions are generated with",
+	Long: `The prompts questions are generated and regenerated with are Go text/templates. The
+built-in ones are used until a file exists at $XDG_CONFIG_HOME/bootdev/prompts/<name>.tmpl
+(~/.config/bootdev by default), or at the path set with generate.prompt_file or
+generate.regenerate_prompt_file in the config. The templates are:
+  questions   generates the questions of a course (quiz-mgmt generate)
+  regenerate  replaces a flagged question (quiz-mgmt flagged --regenerate)
+
+The templates can use:
+  .CourseTitle        the title of the course
+  .Chapter            the title of the chapter of the lessons
+  .Lessons            the lessons, each with .Number, .Title and .Content
+  .Count              the number of questions to generate
+  .Difficulty         easy, medium or hard
+  .QuestionTypes      the rules of each question type to generate
+  .ExistingQuestions  the questions not to repeat
+  .Flagged            in the regenerate template only, the flagged question with
+                      .Question (as JSON), .Type, .Instruction (how to fix it for the
+                      reason it was flagged) and .Note (left by the student)
+
+The model, the maximum answer length and the temperature are set with llm.model,
+llm.max_tokens and llm.temperature in the config.`,
+}
+
+// promptName returns the template named by the optional argument of the prompt commands
+func promptName(args []string) string {
+	if len(args) == 0 {
+		return api.PromptQuestions
+	}
+	return args[0]
+}
+
+var showPromptCmd = &cobra.Command{
+	Use:       "show [questions|regenerate]",
+	Short:     "Print a prompt template in use",
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: api.PromptNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := promptName(args)
+		builtin, _ := cmd.Flags().GetBool("default")
+		if builtin {
+			fmt.Print(api.DefaultPrompt(name))
+			return nil
+		}
+
+		text, source, err := api.PromptText(name)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return nil
+		}
+		if source == "" {
+			fmt.Fprintf(os.Stderr, "Built-in prompt template, override it in %s\n\n", api.PromptPath(name))
+		} else {
+			fmt.Fprintf(os.Stderr, "Prompt template from %s\n\n", source)
+		}
+		fmt.Print(text)
+		return nil
+	},
+}
+
+var editPromptCmd = &cobra.Command{
+	Use:       "edit [questions|regenerate]",
+	Short:     "Edit a prompt template in $EDITOR",
+	Long:      `Opens the prompt template file in $VISUAL or $EDITOR, creating it from the built-in template first if needed, and checks it once saved.`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: api.PromptNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := promptName(args)
+		path := api.PromptPath(name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				fmt.Printf("Error creating %s: %v\n", filepath.Dir(path), err)
+				return nil
+			}
+			if err := os.WriteFile(path, []byte(api.DefaultPrompt(name)), 0644); err != nil {
+				fmt.Printf("Error creating %s: %v\n", path, err)
+				return nil
+			}
+			fmt.Printf("Created %s from the built-in template\n", path)
+		}
+
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+		edit := exec.Command(editor, path)
+		edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := edit.Run(); err != nil {
+			fmt.Printf("Error running %s: %v\n", editor, err)
+			return nil
+		}
+
+		text, _, err := api.PromptText(name)
+		if err == nil {
+			_, err = api.ParsePrompt(name, text)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\nFix it with quiz-mgmt prompt edit %s, or delete %s to use the built-in template again\n", err, name, path)
+			return nil
+		}
+		fmt.Printf("Saved %s\n", path)
+		return nil
+	},
+}
+
+var testPromptCmd = &cobra.Command{
+	Use:   "test <COURSE_UUID>",
+	Short: "Print the prompts a generation would send for a course",
+	Long: `Renders the prompt template with the content of a course, the way quiz-mgmt generate would, and prints the prompt of each chunk of the course. With --send the prompts are sent to the language model and the questions it generates are printed, without saving them.
+
+With --regenerate the argument is the ID of a flagged question instead, and the regenerate template is rendered the way quiz-mgmt flagged --regenerate would.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		send, _ := cmd.Flags().GetBool("send")
+		if regenerate, _ := cmd.Flags().GetBool("regenerate"); regenerate {
+			questionID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				fmt.Printf("Invalid question ID: %s\n", args[0])
+				return nil
+			}
+			testRegeneratePrompt(questionID, send)
+			return nil
+		}
+
+		questionsCount, _ := cmd.Flags().GetInt("questions")
+		if questionsCount < 1 {
+			fmt.Printf("Invalid number of questions: %d\n", questionsCount)
+			return nil
+		}
+		questionTypes, _ := cmd.Flags().GetStringSlice("types")
+		chapter, _ := cmd.Flags().GetString("chapter")
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		difficulty := viper.GetString("generate.difficulty")
+		if cmd.Flags().Changed("difficulty") {
+			difficulty, _ = cmd.Flags().GetString("difficulty")
+		}
+		testPrompt(args[0], chapter, questionsCount, questionTypes, difficulty, send, refresh)
+		return nil
+	},
+}
+
+func testPrompt(courseUUID, chapterRef string, questionsCount int, questionTypes []string, difficulty string, send, refresh bool) {
+	course, err := loadCourse(courseUUID, refresh)
+	if err != nil {
+		fmt.Printf("Error fetching course content: %v\n", err)
+		return
+	}
+	if chapterRef != "" {
+		chapter, err := course.FindChapter(chapterRef)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		course = course.WithChapter(chapter)
+	}
+
+	if send {
+		quiz, report, duplicates, err := generateNewQuestions(course, questionsCount, questionTypes, difficulty, viper.GetFloat64("generate.duplicate_threshold"))
+		printGenerationReport(report)
+		if err != nil {
+			fmt.Printf("Error generating quiz: %v\n", err)
+			return
+		}
+		if len(duplicates) > 0 {
+			fmt.Printf("Skipped %d near-duplicate questions\n\n", len(duplicates))
+		}
+		printGeneratedQuestions(quiz.Questions)
+		fmt.Println("Nothing was saved")
+		return
+	}
+
+	existing, err := dao.GetQuestions(nil, dao.QuestionFilter{CourseUUID: course.UUID, IncludeArchived: true, IncludeFlagged: true})
+	if err != nil {
+		fmt.Printf("Error getting existing questions: %v\n", err)
+		return
+	}
+	prompts, err := api.QuestionPrompts(course, questionsCount, questionTypes, difficulty, existing)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	for i, p := range prompts {
+		fmt.Printf("=== Prompt %d of %d: %s, %d questions, about %d tokens ===\n\n", i+1, len(prompts), p.Chunk, p.Questions, p.Tokens)
+		fmt.Println(p.Prompt)
+	}
+}
+
+func testRegeneratePrompt(questionID int64, send bool) {
+	questions, err := getFlagged(dao.QuestionFilter{Flagged: true, IncludeArchived: true}, []int64{questionID})
+	if err != nil {
+		fmt.Printf("Error getting flagged questions: %v\n", err)
+		return
+	}
+	if len(questions) == 0 {
+		fmt.Printf("No flagged question #%d\n", questionID)
+		return
+	}
+
+	if send {
+		question, err := api.RegenerateQuestion(&questions[0])
+		if err != nil {
+			fmt.Printf("Error regenerating question: %v\n", err)
+			return
+		}
+		printGeneratedQuestions([]dao.Question{question})
+		fmt.Println("Nothing was saved")
+		return
+	}
+
+	prompt, err := api.RegeneratePrompt(&questions[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Println(prompt)
+}
+
+func init() {
+	quizCmd.AddCommand(promptCmd)
+	promptCmd.AddCommand(showPromptCmd)
+	promptCmd.AddCommand(editPromptCmd)
+	promptCmd.AddCommand(testPromptCmd)
+
+	showPromptCmd.Flags().Bool("default", false, "print the built-in template, even when it is overridden")
+
+	testPromptCmd.Flags().IntP("questions", "q", 10, "Number of questions to generate")
+	testPromptCmd.Flags().StringSliceP("types", "t", api.DefaultQuestionTypes, "Question types to generate (multiple_choice, multi_select, ordering, free_response)")
+	testPromptCmd.Flags().String("chapter", "", "only use this chapter (UUID, slug or number)")
+	testPromptCmd.Flags().String("difficulty", api.DefaultDifficulty, "difficulty of the questions (easy, medium or hard)")
+	testPromptCmd.Flags().Bool("send", false, "send the prompts to the language model and print the questions, without saving them")
+	testPromptCmd.Flags().Bool("regenerate", false, "render the regenerate prompt of the flagged question with this ID")
+	testPromptCmd.Flags().Bool("refresh", false, "download the lessons that changed in the course first")
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -9,30 +11,74 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	api "github.com/bootdotdev/bootdev/client"
 	dao "github.com/bootdotdev/bootdev/db"
 	render "github.com/bootdotdev/bootdev/render"
 )
 
-func getCourseContent(uuid string) {
-	course, err := api.FetchCourseAndLessons(uuid)
-	if err != nil {
-		fmt.Printf("Error fetching course content %v", err)
-		return
+func getCourseContent(uuid string, force bool) {
+	if _, err := downloadCourse(uuid, force); err != nil {
+		fmt.Printf("Error fetching course content: %v\n", err)
+	}
+}
+
+// downloadCourse downloads the lessons of a course that changed, or all of them
+// with force, showing the progress, and tells what changed. The course is
+// returned along with the error when only some lessons failed.
+func downloadCourse(uuid string, force bool) (*api.Course, error) {
+	var course *api.Course
+	var report api.RefreshReport
+	err := render.RenderDownload("Downloading lessons...", func(ctx context.Context, onProgress func(api.DownloadProgress)) error {
+		var err error
+		course, report, err = api.RefreshCourse(ctx, uuid, force, onProgress)
+		return err
+	})
+	if course != nil {
+		fmt.Printf("Title: %s\n", course.Title)
+		printRefreshReport(report)
 	}
-	fmt.Printf("Title: %s\n", course.Title)
-	fmt.Printf("Lessons: %d\n", len(course.GetLessons()))
-	for _, l := range course.GetLessons() {
-		fmt.Printf("Lessons %p %s: %d\n", l, l.Slug, len(l.Content))
+	return course, err
+}
+
+// printRefreshReport tells which lessons a download of a course changed
+func printRefreshReport(report api.RefreshReport) {
+	fmt.Printf("Lessons: %d, downloaded %d (%d new), failed %d, skipped %d\n", report.Lessons, report.Downloaded, len(report.Added), report.Failed, report.Skipped())
+	for _, slug := range report.Changed {
+		fmt.Printf("  updated %s\n", slug)
 	}
+	for _, slug := range report.Removed {
+		fmt.Printf("  removed %s\n", slug)
+	}
+	fmt.Println()
+}
+
+// loadCourse returns the local copy of a course, downloading it the first
+// time, or what changed first with refresh
+func loadCourse(uuid string, refresh bool) (*api.Course, error) {
+	if !refresh {
+		course, err := api.LoadLocalCourse(uuid)
+		if err != nil || course != nil {
+			return course, err
+		}
+	}
+	course, err := downloadCourse(uuid, false)
+	var downloadErr *api.DownloadError
+	if errors.As(err, &downloadErr) && !downloadErr.Canceled {
+		// Go on with the lessons that could be downloaded
+		fmt.Printf("Warning: %v\n\n", err)
+		return course, nil
+	}
+	return course, err
 }
 
 func getCompletedCourses() {
-	if courses, err := api.GetUserCourses(); err != nil {
+	if list, err := loadUserCourses(); err != nil {
 		fmt.Printf("Failed to fetch user's courses %s", err)
 	} else {
-		for _, course := range courses {
+		printOfflineBanner(list)
+		for _, course := range list.Courses {
 			if course.IsCompleted() {
 				fmt.Printf("%s (%s); (Completed At %s)\n", course.Title, course.UUID, course.CompletedAt)
 			}
@@ -48,7 +94,7 @@ var quizCmd = &cobra.Command{
 var quizModeCmd = &cobra.Command{
 	Use:          "quiz",
 	Short:        "quiz mode",
-	PreRun:       requireAuth,
+	Long:         `Pick one of your courses and quiz yourself on it, generating questions when it has none. Without network or login, the course list cached the last time is used instead.`,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("")
@@ -63,7 +109,7 @@ var quizModeCmd = &cobra.Command{
 			fmt.Println("Noting to do...")
 			return nil
 		}
-		startQuiz(courseUUID)
+		startQuiz(dao.QuestionFilter{CourseUUID: courseUUID})
 		return nil
 	},
 }
@@ -71,7 +117,6 @@ var quizModeCmd = &cobra.Command{
 var myCompletedCourses = &cobra.Command{
 	Use:          "completed_courses",
 	Short:        "Displays my completed courses",
-	PreRun:       requireAuth,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		getCompletedCourses()
@@ -80,8 +125,9 @@ var myCompletedCourses = &cobra.Command{
 }
 
 var downloadCourseContent = &cobra.Command{
-	Use:          "download_course",
+	Use:          "download_course <COURSE_UUID>",
 	Short:        "Downlaods all lessons from a course and stores its content in the DB",
+	Long:         `Downloads the chapters and lessons of a course into the local copy that generation, quiz-mgmt read and quiz-mgmt search use. Once downloaded, only the lessons whose entry in the course listing changed are downloaded again, or every lesson with --force. The listing doesn't include the content of the lessons, so use --force to pick up a lesson whose content was edited on its own. Lessons are downloaded a few at a time (--concurrency, or download.concurrency in the config), requests the API rate limits or fails are retried, and Ctrl+C stops the download, keeping the lessons already downloaded.`,
 	PreRun:       requireAuth,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -90,7 +136,12 @@ var downloadCourseContent = &cobra.Command{
 			return nil
 		}
 		courseUUID := args[0]
-		getCourseContent(courseUUID)
+		force, _ := cmd.Flags().GetBool("force")
+		if cmd.Flags().Changed("concurrency") {
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			viper.Set("download.concurrency", concurrency)
+		}
+		getCourseContent(courseUUID, force)
 		return nil
 	},
 }
@@ -98,10 +149,13 @@ var downloadCourseContent = &cobra.Command{
 var startQuizCmd = &cobra.Command{
 	Use:   "start <COURSE_UUID>",
 	Short: "Start an interactive quiz for a course",
+	Long:  `Starts an interactive quiz with the due questions of a course, optionally narrowed down to one deck, or to the questions generated from one chapter or lesson (by UUID or slug). It only reads the local database, so it works offline and without logging in.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		courseUUID := args[0]
-		startQuiz(courseUUID)
+		deck, _ := cmd.Flags().GetString("deck")
+		chapter, _ := cmd.Flags().GetString("chapter")
+		lesson, _ := cmd.Flags().GetString("lesson")
+		startQuiz(dao.QuestionFilter{CourseUUID: args[0], Deck: deck, Chapter: chapter, Lesson: lesson})
 		return nil
 	},
 }
@@ -113,7 +167,23 @@ var generateQuizCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		courseUUID := args[0]
 		questionsCount, _ := cmd.Flags().GetInt("questions")
-		generateQuiz(courseUUID, questionsCount)
+		if questionsCount < 1 {
+			fmt.Printf("Invalid number of questions: %d\n", questionsCount)
+			return nil
+		}
+		questionTypes, _ := cmd.Flags().GetStringSlice("types")
+		deck, _ := cmd.Flags().GetString("deck")
+		chapter, _ := cmd.Flags().GetString("chapter")
+		threshold := viper.GetFloat64("generate.duplicate_threshold")
+		if cmd.Flags().Changed("duplicate-threshold") {
+			threshold, _ = cmd.Flags().GetFloat64("duplicate-threshold")
+		}
+		difficulty := viper.GetString("generate.difficulty")
+		if cmd.Flags().Changed("difficulty") {
+			difficulty, _ = cmd.Flags().GetString("difficulty")
+		}
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		generateQuiz(courseUUID, deck, chapter, questionsCount, questionTypes, difficulty, threshold, refresh)
 		return nil
 	},
 }
@@ -168,11 +238,30 @@ func withBlinkingMessage(message string, fn func() error) error {
 	return err
 }
 
+// loadUserCourses returns the course list of the user, from the cache when
+// the user isn't logged in or the API can't be reached
+func loadUserCourses() (*api.CourseList, error) {
+	if err := refreshAuth(); err != nil {
+		return api.CachedUserCourses(err)
+	}
+	return api.LoadUserCourses()
+}
+
+// printOfflineBanner tells when the course list comes from the cache
+func printOfflineBanner(list *api.CourseList) {
+	if list.Offline == nil {
+		return
+	}
+	reason, _, _ := strings.Cut(list.Offline.Error(), "\n")
+	fmt.Printf("\033[1mOFFLINE\033[0m (%s): courses as of %s, questions can only be generated from downloaded courses\n\n", reason, list.CachedAt.Local().Format("2006-01-02 15:04"))
+}
+
 func quizSelect() (string, error) {
-	myCompletedCourses, err := api.GetUserCourses()
+	list, err := loadUserCourses()
 	if err != nil {
 		return "", fmt.Errorf("Error retrieving user's courses: %w\n", err)
 	}
+	myCompletedCourses := list.Courses
 
 	if len(myCompletedCourses) == 0 {
 		return "", fmt.Errorf("No course found")
@@ -184,6 +273,8 @@ func quizSelect() (string, error) {
 	}
 	var coursesWithQuizzes []cwq
 
+	// The banner stays when the selection is cleared
+	printOfflineBanner(list)
 	fmt.Println("Select a course:")
 	lineCount := 1 // "Select a course:" line
 
@@ -233,11 +324,13 @@ func quizSelect() (string, error) {
 	}
 
 	courseWithQuiz := coursesWithQuizzes[selectedIndex]
+	skipped := 0
 
 	if courseWithQuiz.q == nil {
 		msg := "No questions found for this course"
-		if os.Getenv("ANTHROPIC_API_KEY") == "" {
-			fmt.Printf("%s. And I don't see the ANTHROPIC_API_KEY in the environment. Use a mock quiz(y) or quit(q)? [y/q]: ", msg)
+		generator, err := api.NewQuestionGenerator()
+		if err != nil {
+			fmt.Printf("%s. And no language model is available (%v). Use a mock quiz(y) or quit(q)? [y/q]: ", msg, err)
 			lineCount++
 			input, err := reader.ReadString('\n')
 			if err != nil {
@@ -245,13 +338,20 @@ func quizSelect() (string, error) {
 			}
 			input = strings.TrimSpace(input)
 			if input == "y" {
+				pack, err := dao.GetSeedPack(dao.DemoSeedPack)
+				if err != nil {
+					return "", err
+				}
+				if _, err := dao.Seed(nil, pack); err != nil {
+					return "", fmt.Errorf("Error loading the mock quiz: %w", err)
+				}
 				clearLines(lineCount)
-				return "total-recall-uuid", nil
+				return pack.CourseUUID, nil
 			} else {
 				return "", nil
 			}
 		} else {
-			fmt.Printf("%s. I see the ANTHROPIC_API_KEY in the environment. Do you want to ask Claude to generate N questions based on the course content? [N/q]: ", msg)
+			fmt.Printf("%s. Do you want to ask %s to generate N questions based on the course content? [N/q]: ", msg, generator.Name())
 			lineCount++
 
 			input, err := reader.ReadString('\n')
@@ -263,18 +363,22 @@ func quizSelect() (string, error) {
 				return "", nil
 			}
 			questionsNumber, err := strconv.Atoi(input)
-			if err != nil {
+			if err != nil || questionsNumber < 1 {
 				return "", fmt.Errorf("Invalid input for questions number: %s\n", input)
 			}
 
-			err = withBlinkingMessage("Talking to Claude ...", func() error {
-				course, err := api.FetchCourseAndLessons(courseWithQuiz.c.UUID)
+			err = withBlinkingMessage(fmt.Sprintf("Talking to %s ...", generator.Name()), func() error {
+				course, err := api.LoadCourse(courseWithQuiz.c.UUID)
 				if err != nil {
 					return fmt.Errorf("Failed to fetch lessons content for %s: %v", courseWithQuiz.c.Title, err)
 				}
-				quiz, err := api.GenerateQuiz(course, questionsNumber)
+				quiz, _, duplicates, err := generateNewQuestions(course, questionsNumber, api.DefaultQuestionTypes, viper.GetString("generate.difficulty"), viper.GetFloat64("generate.duplicate_threshold"))
 				if err != nil {
-					return fmt.Errorf("Failed to generate quiz questions: %s", input)
+					return fmt.Errorf("Failed to generate quiz questions: %v", err)
+				}
+				skipped = len(duplicates)
+				if len(quiz.Questions) == 0 {
+					return fmt.Errorf("Skipped %d near-duplicate questions, no new questions to add", skipped)
 				}
 				err = dao.CreateQuiz(nil, quiz)
 				if err != nil {
@@ -289,14 +393,15 @@ func quizSelect() (string, error) {
 	}
 
 	clearLines(lineCount)
+	// Printed after clearing, so it stays above the quiz
+	if skipped > 0 {
+		fmt.Printf("Skipped %d near-duplicate questions\n", skipped)
+	}
 	return courseWithQuiz.c.UUID, nil
 }
 
-func startQuiz(courseUUID string) {
-	config := dao.DBConfig{
-		InitFile: "db/init.sql",
-		DBPath:   "data/bootdev.db",
-	}
+func startQuiz(filter dao.QuestionFilter) {
+	config := dao.DefaultDBConfig()
 	db, err := dao.InitializeDatabase(config)
 	if err != nil {
 		fmt.Printf("Error connecting to database: %v\n", err)
@@ -304,7 +409,7 @@ func startQuiz(courseUUID string) {
 	}
 	defer db.Close()
 
-	quiz, err := dao.GetQuiz(db, courseUUID)
+	quiz, err := dao.GetFilteredQuiz(db, filter)
 	if err != nil {
 		fmt.Printf("Error getting quiz: %s", err)
 		os.Exit(1)
@@ -312,53 +417,139 @@ func startQuiz(courseUUID string) {
 	render.RenderQuiz(quiz)
 }
 
-func generateQuiz(courseUUID string, questionsCount int) {
+func generateQuiz(courseUUID, deck, chapterRef string, questionsCount int, questionTypes []string, difficulty string, threshold float64, refresh bool) {
 	fmt.Printf("Generating %d questions for course %s...\n\n", questionsCount, courseUUID)
 
-	// Fetch course content
-	course, err := api.FetchCourseAndLessons(courseUUID)
+	// Local copy of the course content, downloaded the first time
+	course, err := loadCourse(courseUUID, refresh)
 	if err != nil {
 		fmt.Printf("Error fetching course content: %v\n", err)
 		return
 	}
 
 	fmt.Printf("Course: %s\n", course.Title)
+	if chapterRef != "" {
+		chapter, err := course.FindChapter(chapterRef)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		course = course.WithChapter(chapter)
+		if deck == "" {
+			deck = chapter.Slug
+		}
+		fmt.Printf("Chapter: %s\n", chapter.Title)
+	}
 	fmt.Printf("Lessons found: %d\n\n", len(course.GetLessons()))
 
-	// Generate quiz using Claude API
-	quiz, err := api.GenerateQuiz(course, questionsCount)
+	// Generate quiz using the configured language model
+	quiz, report, duplicates, err := generateNewQuestions(course, questionsCount, questionTypes, difficulty, threshold)
+	printGenerationReport(report)
 	if err != nil {
 		fmt.Printf("Error generating quiz: %v\n", err)
 		return
 	}
+	quiz.Deck = deck
+
+	if len(duplicates) > 0 {
+		fmt.Printf("=== Skipped %d near-duplicate questions ===\n\n", len(duplicates))
+		for _, d := range duplicates {
+			fmt.Printf("%s\n", d.Question.QuestionText)
+			if d.Of.QuizID == 0 {
+				fmt.Printf("  %.0f%% similar to another generated question: %s\n\n", d.Similarity*100, d.Of.QuestionText)
+			} else {
+				fmt.Printf("  %.0f%% similar to question #%d: %s\n\n", d.Similarity*100, d.Of.ID, d.Of.QuestionText)
+			}
+		}
+	}
+	if len(quiz.Questions) == 0 {
+		fmt.Println("No new questions to add")
+		return
+	}
+
+	printGeneratedQuestions(quiz.Questions)
 
-	// Print generated questions
+	err = dao.CreateQuiz(nil, quiz)
+	if err != nil {
+		fmt.Printf(("Error saving quiz to the DB: %v"), err)
+		return
+	}
+
+	fmt.Printf("Successfully generated %d questions in deck %s!\n", len(quiz.Questions), quiz.GetDeck())
+}
+
+func printGeneratedQuestions(questions []dao.Question) {
 	fmt.Printf("=== Generated Questions ===\n\n")
-	for i, question := range quiz.Questions {
+	for i, question := range questions {
 		fmt.Printf("Question %d: %s\n", i+1, question.QuestionText)
 
 		choices := question.GetAnswerChoices()
 		for j, choice := range choices {
+			if question.QuestionType == "ordering" {
+				fmt.Printf("  %d. %s\n", j+1, choice)
+				continue
+			}
 			marker := ""
-			if choice == question.CorrectAnswer {
+			if question.IsCorrectChoice(choice) {
 				marker = " ✅"
 			}
 			fmt.Printf("  %c) %s%s\n", 'A'+j, choice, marker)
 		}
 
+		if question.QuestionType == "free_response" {
+			fmt.Printf("  Reference answer: %s\n", question.CorrectAnswer)
+			fmt.Printf("  Rubric: %s\n", question.Rubric)
+		}
+
 		if question.Explanation != "" {
 			fmt.Printf("  Explanation: %s\n", question.Explanation)
 		}
 		fmt.Println()
 	}
+}
 
-	err = dao.CreateQuiz(nil, quiz)
+// generateNewQuestions generates questions for a course and drops the ones too
+// similar to the questions the course already has, or to each other
+func generateNewQuestions(course *api.Course, questionsCount int, questionTypes []string, difficulty string, threshold float64) (*dao.Quiz, api.GenerationReport, []dao.Duplicate, error) {
+	existing, err := dao.GetQuestions(nil, dao.QuestionFilter{CourseUUID: course.UUID, IncludeArchived: true, IncludeFlagged: true})
 	if err != nil {
-		fmt.Printf(("Error saving quiz to the DB: %v"), err)
-		return
+		return nil, api.GenerationReport{}, nil, err
+	}
+
+	quiz, report, err := api.GenerateQuiz(course, questionsCount, questionTypes, difficulty, existing)
+	if err != nil {
+		return nil, report, nil, err
 	}
 
-	fmt.Printf("Successfully generated %d questions!\n", len(quiz.Questions))
+	var duplicates []dao.Duplicate
+	quiz.Questions, duplicates = dao.RemoveDuplicates(existing, quiz.Questions, threshold)
+	return quiz, report, duplicates, nil
+}
+
+// printGenerationReport lists the generated questions that were rejected as
+// invalid and the calls that failed
+func printGenerationReport(report api.GenerationReport) {
+	if len(report.Rejected) > 0 {
+		fmt.Printf("=== Rejected %d invalid questions ===\n\n", len(report.Rejected))
+		for _, r := range report.Rejected {
+			fmt.Printf("%s\n", r.Question)
+			fmt.Printf("  %s (%s)\n\n", r.Reason, r.Chunk)
+		}
+	}
+	if len(report.Errors) > 0 {
+		fmt.Printf("=== %d failed calls ===\n\n", len(report.Errors))
+		for _, err := range report.Errors {
+			fmt.Printf("%v\n", err)
+		}
+		fmt.Println()
+	}
+	if report.Calls > 0 {
+		fmt.Printf("Accepted %d of %d requested questions in %d calls", report.Accepted, report.Requested, report.Calls)
+		if missing := report.Missing(); missing > 0 {
+			fmt.Printf(", %d could not be generated", missing)
+		}
+		fmt.Printf("\n\n")
+	}
 }
 
 func init() {
@@ -371,4 +562,26 @@ func init() {
 
 	// Add --questions flag with default value of 10
 	generateQuizCmd.Flags().IntP("questions", "q", 10, "Number of questions to generate")
+	generateQuizCmd.Flags().StringSliceP("types", "t", api.DefaultQuestionTypes, "Question types to generate (multiple_choice, multi_select, ordering, free_response)")
+	generateQuizCmd.Flags().String("deck", "", "deck to add the questions to (default is the chapter slug with --chapter, or default)")
+	generateQuizCmd.Flags().String("chapter", "", "only generate questions from this chapter (UUID, slug or number)")
+	// Defaults to the generate.duplicate_threshold config value
+	generateQuizCmd.Flags().Float64("duplicate-threshold", dao.DefaultDuplicateThreshold, "Similarity (0 to 1) from which a generated question is skipped as a near duplicate, above 1 to keep them all")
+	viper.SetDefault("generate.duplicate_threshold", dao.DefaultDuplicateThreshold)
+	viper.SetDefault("generate.chunk_tokens", api.DefaultChunkTokens)
+	viper.SetDefault("generate.concurrency", api.DefaultConcurrency)
+	// Defaults to the generate.difficulty config value
+	generateQuizCmd.Flags().String("difficulty", api.DefaultDifficulty, "difficulty of the questions (easy, medium or hard)")
+	viper.SetDefault("generate.difficulty", api.DefaultDifficulty)
+
+	generateQuizCmd.Flags().Bool("refresh", false, "download the lessons that changed in the course before generating")
+
+	downloadCourseContent.Flags().Bool("force", false, "download every lesson again, even the ones that did not change")
+	// Defaults to the download.concurrency config value
+	downloadCourseContent.Flags().Int("concurrency", api.DefaultDownloadConcurrency, "number of lessons downloaded at the same time")
+	viper.SetDefault("download.concurrency", api.DefaultDownloadConcurrency)
+
+	startQuizCmd.Flags().String("deck", "", "only ask questions from this deck")
+	startQuizCmd.Flags().String("chapter", "", "only ask questions generated from this chapter (UUID or slug)")
+	startQuizCmd.Flags().String("lesson", "", "only ask questions generated from this lesson (UUID or slug)")
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	render "github.com/bootdotdev/bootdev/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:          "review",
+	Short:        "Daily review of the due questions from all your course quizzes",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		size := viper.GetInt("review.session_size")
+		if cmd.Flags().Changed("size") {
+			size, _ = cmd.Flags().GetInt("size")
+		}
+		startReview(size)
+		return nil
+	},
+}
+
+func startReview(size int) {
+	config := dao.DefaultDBConfig()
+	db, err := dao.InitializeDatabase(config)
+	if err != nil {
+		fmt.Printf("Error connecting to database: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	session, err := dao.GetReviewSession(db, size)
+	if err != nil {
+		fmt.Printf("Error getting review session: %v\n", err)
+		return
+	}
+	if session == nil {
+		fmt.Println("Nothing to review today. Come back tomorrow!")
+		return
+	}
+	render.RenderReview(session)
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+
+	// Defaults to the review.session_size config value
+	reviewCmd.Flags().IntP("size", "n", 20, "Maximum number of questions in the session (0 for no limit)")
+	viper.SetDefault("review.session_size", 20)
+}
@@ -2,18 +2,21 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"time"
 
 	api "github.com/bootdotdev/bootdev/client"
+	dao "github.com/bootdotdev/bootdev/db"
 	"github.com/bootdotdev/bootdev/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var dbPath string
 
 var rootCmd = &cobra.Command{
 	Use:   "bootdev",
@@ -35,6 +38,8 @@ func Execute(currentVersion string) error {
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.bootdev.yaml)")
+	rootCmd.PersistentFlags().StringVar(&dbPath, "db", "", "quiz database file (default is $XDG_DATA_HOME/bootdev/bootdev.db)")
+	viper.BindPFlag("db_path", rootCmd.PersistentFlags().Lookup("db"))
 }
 
 func readViperConfig(paths []string) error {
@@ -55,6 +60,18 @@ func initConfig() {
 	viper.SetDefault("access_token", "")
 	viper.SetDefault("refresh_token", "")
 	viper.SetDefault("last_refresh", 0)
+	viper.SetDefault("quiz.scheduler", dao.DefaultScheduler)
+	viper.SetDefault("quiz.fill_blank.case_sensitive", false)
+	viper.SetDefault("quiz.fill_blank.strip_whitespace", true)
+	viper.SetDefault("quiz.fill_blank.strip_punctuation", true)
+	// At most one typo per five characters of the answer, see AnswerMatching
+	viper.SetDefault("quiz.fill_blank.max_typos", 1)
+	viper.SetDefault("quiz.multi_select.partial_credit", true)
+	viper.SetDefault("llm.provider", api.DefaultProvider)
+	viper.SetDefault("llm.base_url", "")
+	viper.SetDefault("llm.model", "")
+	viper.SetDefault("llm.api_key", "")
+	viper.SetDefault("llm.max_tokens", api.DefaultMaxTokens)
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -114,33 +131,39 @@ func requireUpdated(cmd *cobra.Command, args []string) {
 // automatically refresh the tokens, if necessary, and prompt
 // the user to re-login if anything goes wrong.
 func requireAuth(cmd *cobra.Command, args []string) {
-	promptLoginAndExitIf := func(condition bool) {
-		if condition {
-			fmt.Fprintln(os.Stderr, "You must be logged in to use that command.")
-			fmt.Fprintln(os.Stderr, "Please run 'bootdev login' first.")
-			os.Exit(1)
-		}
+	if err := refreshAuth(); err != nil {
+		fmt.Fprintln(os.Stderr, "You must be logged in to use that command.")
+		fmt.Fprintln(os.Stderr, "Please run 'bootdev login' first.")
+		os.Exit(1)
 	}
+}
 
+// refreshAuth refreshes the tokens when they are getting stale, for the
+// commands that can do without the API when the user isn't logged in or
+// is offline.
+func refreshAuth() error {
 	access_token := viper.GetString("access_token")
-	promptLoginAndExitIf(access_token == "")
+	if access_token == "" {
+		return errors.New("not logged in")
+	}
 
 	// We only refresh if our token is getting stale.
 	last_refresh := viper.GetInt64("last_refresh")
 	if time.Now().Add(-time.Minute*55).Unix() <= last_refresh {
-		return
+		return nil
 	}
 
 	creds, err := api.FetchAccessToken()
-	promptLoginAndExitIf(err != nil)
+	if err != nil {
+		return fmt.Errorf("failed to refresh the login: %w", err)
+	}
 	if creds.AccessToken == "" || creds.RefreshToken == "" {
-		promptLoginAndExitIf(err != nil)
+		return errors.New("failed to refresh the login")
 	}
 
 	viper.Set("access_token", creds.AccessToken)
 	viper.Set("refresh_token", creds.RefreshToken)
 	viper.Set("last_refresh", time.Now().Unix())
 
-	err = viper.WriteConfig()
-	promptLoginAndExitIf(err != nil)
+	return viper.WriteConfig()
 }
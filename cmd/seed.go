@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/cobra"
+)
+
+var dbSeedCmd = &cobra.Command{
+	Use:   "seed [pack...]",
+	Short: "Load demo quizzes",
+	Long:  `Loads the named demo quiz packs into the database, or all of them if none is given. Use --list to see the available packs.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, _ := cmd.Flags().GetBool("list")
+		if list {
+			listSeedPacks()
+			return nil
+		}
+		packs, err := resolveSeedPacks(args)
+		if err != nil {
+			return err
+		}
+		seedDatabase(packs)
+		return nil
+	},
+}
+
+var dbUnseedCmd = &cobra.Command{
+	Use:   "unseed [pack...]",
+	Short: "Remove demo quizzes",
+	Long:  `Removes the named demo quiz packs and their answer history from the database, or all of them if none is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packs, err := resolveSeedPacks(args)
+		if err != nil {
+			return err
+		}
+		unseedDatabase(packs)
+		return nil
+	},
+}
+
+func resolveSeedPacks(names []string) ([]dao.SeedPack, error) {
+	if len(names) == 0 {
+		return dao.SeedPacks(), nil
+	}
+	packs := make([]dao.SeedPack, 0, len(names))
+	for _, name := range names {
+		pack, err := dao.GetSeedPack(name)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+	return packs, nil
+}
+
+func listSeedPacks() {
+	config := dao.DefaultDBConfig()
+	db, err := dao.InitializeDatabase(config)
+	if err != nil {
+		fmt.Printf("Error connecting to database: %v\n", err)
+		return
+	}
+	defer db.Close()
+
+	fmt.Println("=== Seed Packs ===")
+	for _, pack := range dao.SeedPacks() {
+		seeded, err := dao.IsSeeded(db, pack)
+		if err != nil {
+			fmt.Printf("Error checking seed packs: %v\n", err)
+			return
+		}
+		mark := " "
+		if seeded {
+			mark = "x"
+		}
+		fmt.Printf("  [%s] %-14s %s\n", mark, pack.Name, pack.Description)
+	}
+}
+
+func seedDatabase(packs []dao.SeedPack) {
+	for _, pack := range packs {
+		seeded, err := dao.Seed(nil, pack)
+		if err != nil {
+			fmt.Printf("Error seeding database: %v\n", err)
+			return
+		}
+		if seeded {
+			fmt.Printf("Loaded %s\n", pack.Name)
+		} else {
+			fmt.Printf("%s is already loaded\n", pack.Name)
+		}
+	}
+}
+
+func unseedDatabase(packs []dao.SeedPack) {
+	for _, pack := range packs {
+		removed, err := dao.Unseed(nil, pack)
+		if err != nil {
+			fmt.Printf("Error unseeding database: %v\n", err)
+			return
+		}
+		if removed {
+			fmt.Printf("Removed %s\n", pack.Name)
+		} else {
+			fmt.Printf("%s is not loaded\n", pack.Name)
+		}
+	}
+}
+
+func init() {
+	dbCmd.AddCommand(dbSeedCmd)
+	dbCmd.AddCommand(dbUnseedCmd)
+	dbSeedCmd.Flags().BoolP("list", "l", false, "list the available packs")
+}
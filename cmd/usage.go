@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	api "github.com/bootdotdev/bootdev/client"
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show the calls made to language models and what they cost",
+	Long: `Sums up every call made to generate, regenerate and grade questions, per course and per
+month: the number of calls, the input and output tokens reported by the provider, the cost
+estimated from them, and how many of the generated questions are still in the bank. Costs
+use the list price of known hosted models, or llm.price.input and llm.price.output from
+the config (USD per million tokens).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		courseUUID, _ := cmd.Flags().GetString("course")
+		showUsage(courseUUID)
+		return nil
+	},
+}
+
+var reparseCmd = &cobra.Command{
+	Use:   "reparse <RUN_ID>",
+	Short: "Parse the response of a generation run again, without calling the model",
+	Long: `Every response of the language model is kept. When a generation failed to parse a response,
+the error tells the ID of its run: reparse reads the stored response again, and adds its
+valid questions to the course like quiz-mgmt generate would, without paying for another call.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			fmt.Printf("Invalid run ID: %s\n", args[0])
+			return nil
+		}
+		deck, _ := cmd.Flags().GetString("deck")
+		threshold := viper.GetFloat64("generate.duplicate_threshold")
+		if cmd.Flags().Changed("duplicate-threshold") {
+			threshold, _ = cmd.Flags().GetFloat64("duplicate-threshold")
+		}
+		reparseRun(runID, deck, threshold)
+		return nil
+	},
+}
+
+func showUsage(courseUUID string) {
+	byCourse, err := dao.GetUsageByCourse(nil, courseUUID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if len(byCourse) == 0 {
+		fmt.Println("No calls made to language models yet")
+		return
+	}
+	byMonth, err := dao.GetUsageByMonth(nil, courseUUID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("=== Per course ===\n\n")
+	printUsage("COURSE", byCourse)
+	fmt.Printf("\n=== Per month ===\n\n")
+	printUsage("MONTH", byMonth)
+}
+
+func printUsage(key string, usage []dao.Usage) {
+	var total dao.Usage
+	fmt.Printf("%-36s %7s %12s %12s %9s %10s\n", key, "CALLS", "INPUT", "OUTPUT", "COST", "QUESTIONS")
+	for _, u := range usage {
+		fmt.Printf("%-36s %7d %12d %12d %9s %10d\n", u.Key, u.Calls, u.InputTokens, u.OutputTokens, fmt.Sprintf("$%.2f", u.Cost), u.Questions)
+		total.Calls += u.Calls
+		total.InputTokens += u.InputTokens
+		total.OutputTokens += u.OutputTokens
+		total.Cost += u.Cost
+		total.Questions += u.Questions
+	}
+	fmt.Printf("%-36s %7d %12d %12d %9s %10d\n", "TOTAL", total.Calls, total.InputTokens, total.OutputTokens, fmt.Sprintf("$%.2f", total.Cost), total.Questions)
+}
+
+func reparseRun(runID int64, deck string, threshold float64) {
+	run, err := dao.GetGenerationRun(nil, runID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Run #%d: %s call to %s %s for course %s on %s\n\n", run.ID, run.Kind, run.Provider, run.Model, run.CourseUUID, run.CreatedAt.Local().Format("2006-01-02 15:04"))
+
+	questions, report, err := api.ReparseRun(run)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	printGenerationReport(report)
+
+	existing, err := dao.GetQuestions(nil, dao.QuestionFilter{CourseUUID: run.CourseUUID, IncludeArchived: true, IncludeFlagged: true})
+	if err != nil {
+		fmt.Printf("Error getting existing questions: %v\n", err)
+		return
+	}
+	questions, duplicates := dao.RemoveDuplicates(existing, questions, threshold)
+	if len(duplicates) > 0 {
+		fmt.Printf("Skipped %d questions already in the course, or near duplicates\n\n", len(duplicates))
+	}
+	if len(questions) == 0 {
+		fmt.Println("No new questions to add")
+		return
+	}
+
+	printGeneratedQuestions(questions)
+	quiz := &dao.Quiz{CourseUUID: run.CourseUUID, Deck: deck, Questions: questions}
+	if err := dao.CreateQuiz(nil, quiz); err != nil {
+		fmt.Printf("Error saving quiz to the DB: %v\n", err)
+		return
+	}
+	fmt.Printf("Added %d questions to deck %s\n", len(questions), quiz.GetDeck())
+}
+
+func init() {
+	quizCmd.AddCommand(usageCmd)
+	quizCmd.AddCommand(reparseCmd)
+
+	usageCmd.Flags().String("course", "", "only the calls made for this course")
+
+	reparseCmd.Flags().String("deck", "", "deck to add the questions to (default is default)")
+	// Defaults to the generate.duplicate_threshold config value
+	reparseCmd.Flags().Float64("duplicate-threshold", dao.DefaultDuplicateThreshold, "Similarity (0 to 1) from which a question is skipped as a near duplicate, above 1 to keep them all")
+}
@@ -0,0 +1,489 @@
+package dao
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Anki packages (.apkg) are zip files holding a SQLite collection in the
+// legacy schema (version 11) that every Anki release can import, and a media
+// manifest. Only the tables and columns Anki needs to import notes are filled.
+const ankiSchema = `
+CREATE TABLE col (
+	id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+	ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+	conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+	id integer primary key, guid text not null, mid integer not null, mod integer not null,
+	usn integer not null, tags text not null, flds text not null, sfld integer not null,
+	csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+	id integer primary key, nid integer not null, did integer not null, ord integer not null,
+	mod integer not null, usn integer not null, type integer not null, queue integer not null,
+	due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+	lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+	flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+	id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+	ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+	type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// ankiModelID is fixed so importing an updated deck reuses the same note type
+const ankiModelID int64 = 1717171717001
+
+// ankiDeckPrefix is prepended to the course UUID to name exported decks, named
+// decks of a course become its subdecks
+const ankiDeckPrefix = "bootdev::"
+
+const ankiCSS = `.card {
+ font-family: arial;
+ font-size: 20px;
+ text-align: center;
+ color: black;
+ background-color: white;
+}
+.choices, .answers, .accepted {
+ display: inline-block;
+ text-align: left;
+}
+.rubric, .explanation {
+ font-size: 16px;
+ color: #555;
+}`
+
+// writeAnkiPackage writes the questions of a quiz file as an Anki deck, one
+// basic note per question, the question (and choices) on the front, the
+// answer and explanation on the back
+func writeAnkiPackage(w io.Writer, f *QuizFile) error {
+	dir, err := os.MkdirTemp("", "bootdev-apkg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	collectionPath := filepath.Join(dir, "collection.anki2")
+	if err := writeAnkiCollection(collectionPath, f); err != nil {
+		return fmt.Errorf("failed to build the Anki collection: %w", err)
+	}
+	collection, err := os.ReadFile(collectionPath)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	cw, err := zw.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	if _, err := cw.Write(collection); err != nil {
+		return err
+	}
+	mw, err := zw.Create("media")
+	if err != nil {
+		return err
+	}
+	if _, err := mw.Write([]byte("{}")); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeAnkiCollection(path string, f *QuizFile) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(ankiSchema); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deckName := ankiDeckPrefix + f.CourseUUID
+	if deck := f.GetDeck(); deck != DefaultDeck {
+		deckName += "::" + deck
+	}
+	deckID := ankiDeckID(deckName)
+	models, decks, dconf, conf := ankiCollectionConfig(deckID, deckName, now)
+	_, err = tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		now.Unix(), now.UnixMilli(), now.UnixMilli(), conf, models, decks, dconf)
+	if err != nil {
+		return err
+	}
+
+	baseID := now.UnixMilli()
+	for i, qf := range f.Questions {
+		q, err := qf.toQuestion(f.CourseUUID)
+		if err != nil {
+			return fmt.Errorf("question %d: %w", i+1, err)
+		}
+		front, back := ankiFields(qf)
+		noteID := baseID + int64(i)
+		_, err = tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, q.ContentHash()[:16], ankiModelID, now.Unix(), " bootdev "+qf.Type+" ",
+			front+"\x1f"+back, stripHTML(front), ankiChecksum(front))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+			noteID, noteID, deckID, now.Unix(), i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ankiFields renders the front and back of a question as HTML. The markup is
+// parsed back by parseAnkiNote, keep both in sync.
+func ankiFields(q QuestionFile) (string, string) {
+	list := func(tag, class string, items []string) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, `<%s class="%s">`, tag, class)
+		for _, item := range items {
+			fmt.Fprintf(&b, "<li>%s</li>", ankiText(item))
+		}
+		fmt.Fprintf(&b, "</%s>", tag)
+		return b.String()
+	}
+
+	front := ankiText(q.Question)
+	var back string
+	switch q.Type {
+	case "multiple_choice":
+		front += "<br>" + list("ol", "choices", q.Choices)
+		back = ankiText(q.Answer)
+	case "multi_select":
+		front += "<br>" + list("ol", "choices", q.Choices)
+		back = list("ul", "answers", q.Answers)
+	case "ordering":
+		shuffled := append([]string{}, q.Answers...)
+		shuffle(shuffled)
+		front += "<br>" + list("ul", "choices", shuffled)
+		back = list("ol", "answers", q.Answers)
+	default:
+		back = ankiText(q.Answer)
+		if len(q.AcceptedAnswers) > 0 {
+			back += list("ul", "accepted", q.AcceptedAnswers)
+		}
+		if q.Rubric != "" {
+			back += `<div class="rubric">` + ankiText(q.Rubric) + "</div>"
+		}
+	}
+	if q.Explanation != "" {
+		back += `<hr><div class="explanation">` + ankiText(q.Explanation) + "</div>"
+	}
+	return front, back
+}
+
+var (
+	ankiListItem = regexp.MustCompile(`(?s)<li>(.*?)</li>`)
+	ankiChoices  = regexp.MustCompile(`<br><[ou]l class="choices">`)
+)
+
+// parseAnkiNote reads back a note written by ankiFields
+func parseAnkiNote(questionType, front, back string) QuestionFile {
+	listItems := func(s string) []string {
+		var items []string
+		for _, match := range ankiListItem.FindAllStringSubmatch(s, -1) {
+			items = append(items, stripHTML(match[1]))
+		}
+		return items
+	}
+
+	q := QuestionFile{Type: questionType}
+	question, choices := front, ""
+	if loc := ankiChoices.FindStringIndex(front); loc != nil {
+		question, choices = front[:loc[0]], front[loc[0]:]
+	}
+	q.Question = stripHTML(question)
+
+	answer, explanation, _ := strings.Cut(back, "<hr>")
+	q.Explanation = stripHTML(explanation)
+	answer, rubric, _ := strings.Cut(answer, `<div class="rubric">`)
+	q.Rubric = stripHTML(rubric)
+	answer, accepted, _ := strings.Cut(answer, `<ul class="accepted">`)
+	q.AcceptedAnswers = listItems(accepted)
+
+	switch questionType {
+	case "multi_select":
+		q.Choices = listItems(choices)
+		q.Answers = listItems(answer)
+	case "ordering":
+		q.Answers = listItems(answer)
+	case "multiple_choice":
+		q.Choices = listItems(choices)
+		q.Answer = stripHTML(answer)
+	default:
+		q.Answer = stripHTML(answer)
+	}
+	return q
+}
+
+func ankiText(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+}
+
+// ankiDeckID derives the deck id from its name, so exporting a course again
+// updates the same deck
+func ankiDeckID(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64() & (1<<52 - 1))
+}
+
+// ankiChecksum is the first 8 hex digits of the SHA-1 of the sort field, as Anki computes it
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(stripHTML(field)))
+	checksum, _ := strconv.ParseInt(fmt.Sprintf("%x", sum[:4]), 16, 64)
+	return checksum
+}
+
+func ankiCollectionConfig(deckID int64, deckName string, now time.Time) (models, decks, dconf, conf string) {
+	field := func(name string, ord int) map[string]any {
+		return map[string]any{"name": name, "ord": ord, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}}
+	}
+	model := map[string]any{
+		"id":    ankiModelID,
+		"name":  "bootdev quiz",
+		"type":  0,
+		"mod":   now.Unix(),
+		"usn":   -1,
+		"sortf": 0,
+		"did":   deckID,
+		"tmpls": []any{map[string]any{
+			"name":  "Card 1",
+			"ord":   0,
+			"qfmt":  "{{Front}}",
+			"afmt":  "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		}},
+		"flds":      []any{field("Front", 0), field("Back", 1)},
+		"css":       ankiCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []any{},
+		"vers":      []any{},
+		"req":       []any{[]any{0, "all", []any{0}}},
+	}
+	deck := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": "", "mod": now.Unix(), "usn": -1, "collapsed": false,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+			"dyn": 0, "conf": 1, "extendNew": 10, "extendRev": 50,
+		}
+	}
+	deckConf := map[string]any{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+		"new":   map[string]any{"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true, "separate": true},
+		"rev":   map[string]any{"perDay": 100, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "bury": true, "minSpace": 1},
+		"lapse": map[string]any{"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0},
+	}
+	collectionConf := map[string]any{
+		"activeDecks": []int64{deckID}, "curDeck": deckID, "curModel": ankiModelID, "newSpread": 0,
+		"collapseTime": 1200, "timeLim": 0, "estTimes": true, "dueCounts": true, "sortType": "noteFld", "sortBackwards": false, "nextPos": 1,
+	}
+
+	marshal := func(v any) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	models = marshal(map[string]any{strconv.FormatInt(ankiModelID, 10): model})
+	decks = marshal(map[string]any{"1": deck(1, "Default"), strconv.FormatInt(deckID, 10): deck(deckID, deckName)})
+	dconf = marshal(map[string]any{"1": deckConf})
+	conf = marshal(collectionConf)
+	return
+}
+
+// readAnkiPackage reads the first two fields of every note of an Anki package
+// into a quiz file. Notes not exported by bootdev become fill_blank questions
+// with the back as the answer. The course UUID and deck are taken from the
+// name of the deck holding most cards.
+func readAnkiPackage(data []byte) (*QuizFile, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not an Anki package: %w", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range zr.File {
+		files[file.Name] = file
+	}
+	collection, ok := files["collection.anki21"]
+	if !ok {
+		if _, newFormat := files["collection.anki21b"]; newFormat {
+			return nil, fmt.Errorf("the deck was exported in the latest Anki format, export it again with \"Support older Anki versions\" checked")
+		}
+		if collection, ok = files["collection.anki2"]; !ok {
+			return nil, fmt.Errorf("not an Anki package: no collection found")
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "bootdev-apkg")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	collectionPath := filepath.Join(dir, "collection.anki2")
+	if err := extractZipFile(collection, collectionPath); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", collectionPath+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	deckName, err := ankiMainDeck(db)
+	if err != nil {
+		return nil, err
+	}
+	courseUUID, deck, _ := strings.Cut(strings.TrimPrefix(deckName, ankiDeckPrefix), "::")
+
+	rows, err := db.Query(`SELECT flds, tags FROM notes ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Anki notes: %w", err)
+	}
+	defer rows.Close()
+
+	f := &QuizFile{Version: QuizFileVersion, CourseUUID: courseUUID, Deck: deck}
+	for rows.Next() {
+		var flds, tags string
+		if err := rows.Scan(&flds, &tags); err != nil {
+			return nil, fmt.Errorf("failed to read Anki notes: %w", err)
+		}
+		fields := strings.Split(flds, "\x1f")
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Notes exported by bootdev are tagged with their question type
+		tagList := strings.Fields(tags)
+		if slices.Contains(tagList, "bootdev") && len(tagList) == 2 {
+			questionType := tagList[0]
+			if questionType == "bootdev" {
+				questionType = tagList[1]
+			}
+			q := parseAnkiNote(questionType, fields[0], fields[1])
+			if q.Validate() == nil {
+				f.Questions = append(f.Questions, q)
+				continue
+			}
+		}
+
+		front, back := stripHTML(fields[0]), stripHTML(fields[1])
+		if front == "" || back == "" {
+			continue
+		}
+		f.Questions = append(f.Questions, QuestionFile{Type: "fill_blank", Question: front, Answer: back})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func extractZipFile(file *zip.File, path string) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// ankiMainDeck returns the name of the deck holding most cards
+func ankiMainDeck(db *sql.DB) (string, error) {
+	var decksJSON string
+	if err := db.QueryRow(`SELECT decks FROM col`).Scan(&decksJSON); err != nil {
+		return "", fmt.Errorf("failed to read Anki decks: %w", err)
+	}
+	decks := map[string]struct {
+		Name string `json:"name"`
+	}{}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return "", fmt.Errorf("failed to read Anki decks: %w", err)
+	}
+
+	var deckID int64
+	err := db.QueryRow(`SELECT did FROM cards GROUP BY did ORDER BY COUNT(*) DESC LIMIT 1`).Scan(&deckID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("the Anki deck is empty")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read Anki cards: %w", err)
+	}
+
+	name := decks[strconv.FormatInt(deckID, 10)].Name
+	if name == "" {
+		name = "anki"
+	}
+	return name, nil
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</?(div|p|ul|ol|li)(\s[^>]*)?>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// stripHTML turns an Anki field into plain text
+func stripHTML(s string) string {
+	s = htmlBreaks.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,121 @@
+package dao
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnkiPackageRoundTrip(t *testing.T) {
+	f := &QuizFile{
+		Version:    QuizFileVersion,
+		CourseUUID: "3b39d0f6-f944-4f1b-832d-a1daba32eda4",
+		Deck:       "joins",
+		Questions: []QuestionFile{
+			{
+				Type:        "multiple_choice",
+				Question:    "Which join keeps every row of the left table?\nPick one.",
+				Choices:     []string{"INNER", "LEFT", "<none>"},
+				Answer:      "LEFT",
+				Explanation: "LEFT fills the missing right side with NULL",
+			},
+			{
+				Type:     "multi_select",
+				Question: "Which are aggregate functions?",
+				Choices:  []string{"COUNT", "LOWER", "SUM"},
+				Answers:  []string{"COUNT", "SUM"},
+			},
+			{
+				Type:            "fill_blank",
+				Question:        "A _____ key uniquely identifies each row.",
+				Answer:          "primary",
+				AcceptedAnswers: []string{"PK", "primary key"},
+			},
+			{
+				Type:     "ordering",
+				Question: "Put the TCP handshake in order",
+				Answers:  []string{"SYN", "SYN-ACK", "ACK"},
+			},
+			{
+				Type:        "free_response",
+				Question:    "Explain what an index is for.",
+				Answer:      "Finding rows without scanning the table.",
+				Rubric:      "Mentions lookups & scans",
+				Explanation: "Indexes trade writes for reads",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeQuizFile(&buf, f, "apkg"); err != nil {
+		t.Fatalf("EncodeQuizFile: %v", err)
+	}
+	got, err := DecodeQuizFile(&buf, "apkg")
+	if err != nil {
+		t.Fatalf("DecodeQuizFile: %v", err)
+	}
+	if got.CourseUUID != f.CourseUUID || got.Deck != f.Deck {
+		t.Errorf("course %q deck %q, want %q and %q", got.CourseUUID, got.Deck, f.CourseUUID, f.Deck)
+	}
+	if !reflect.DeepEqual(got.Questions, f.Questions) {
+		t.Errorf("questions =\n%+v\nwant\n%+v", got.Questions, f.Questions)
+	}
+}
+
+func TestReadAnkiPackageBasicNote(t *testing.T) {
+	// A deck made in Anki: Basic notes with HTML fields and tags of the user's own
+	dir := t.TempDir()
+	collectionPath := filepath.Join(dir, "collection.anki2")
+	f := &QuizFile{Version: QuizFileVersion, CourseUUID: "course", Questions: []QuestionFile{
+		{Type: "fill_blank", Question: "placeholder", Answer: "placeholder"},
+		{Type: "fill_blank", Question: "placeholder", Answer: "placeholder"},
+	}}
+	if err := writeAnkiCollection(collectionPath, f); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", collectionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := []struct{ fields, tags string }{
+		{"What does <b>SQL</b> stand for?\x1f<div>Structured Query Language</div>", " databases "},
+		{"Tagged like ours\x1fbut not a bootdev note", " bootdev essay "},
+	}
+	for i, note := range notes {
+		if _, err := db.Exec(`UPDATE notes SET flds = ?, tags = ? WHERE id = (SELECT id FROM notes ORDER BY id LIMIT 1 OFFSET ?)`, note.fields, note.tags, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("collection.anki2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, err := os.ReadFile(collectionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(collection)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readAnkiPackage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("readAnkiPackage: %v", err)
+	}
+	want := []QuestionFile{
+		{Type: "fill_blank", Question: "What does SQL stand for?", Answer: "Structured Query Language"},
+		{Type: "fill_blank", Question: "Tagged like ours", Answer: "but not a bootdev note"},
+	}
+	if !reflect.DeepEqual(got.Questions, want) {
+		t.Errorf("questions =\n%+v\nwant\n%+v", got.Questions, want)
+	}
+}
@@ -0,0 +1,372 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Course is the local copy of a course, with its chapters and lessons in order
+type Course struct {
+	UUID        string
+	Slug        string
+	Title       string
+	Description string
+	Chapters    []Chapter
+	FetchedAt   time.Time
+}
+
+type Chapter struct {
+	UUID    string
+	Slug    string
+	Title   string
+	Lessons []Lesson
+}
+
+type Lesson struct {
+	UUID    string
+	Slug    string
+	Title   string
+	Content string
+	// ContentHash is the sha256 of the content, empty until it is downloaded
+	ContentHash string
+	// ListingHash is the sha256 of the lesson in the course listing, which
+	// changes when the lesson does
+	ListingHash string
+	// FetchedAt is when the content was downloaded, zero until then
+	FetchedAt time.Time
+}
+
+// LessonCount returns the number of lessons in the course
+func (c *Course) LessonCount() int {
+	count := 0
+	for _, chapter := range c.Chapters {
+		count += len(chapter.Lessons)
+	}
+	return count
+}
+
+// SaveCourse stores a course, replacing its chapters and lessons, so the ones
+// removed from the course are removed from the local copy too
+func SaveCourse(db *sql.DB, course *Course) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO courses (uuid, slug, title, description, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (uuid) DO UPDATE SET slug = excluded.slug, title = excluded.title,
+			description = excluded.description, fetched_at = excluded.fetched_at
+	`, course.UUID, course.Slug, course.Title, course.Description, formatTime(course.FetchedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save course %s: %w", course.UUID, err)
+	}
+	for _, table := range []string{"lessons", "chapters"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE course_uuid = ?`, course.UUID); err != nil {
+			return fmt.Errorf("failed to replace the %s of course %s: %w", table, course.UUID, err)
+		}
+	}
+
+	for i, chapter := range course.Chapters {
+		_, err := tx.Exec(`INSERT INTO chapters (uuid, course_uuid, slug, title, position) VALUES (?, ?, ?, ?, ?)`,
+			chapter.UUID, course.UUID, chapter.Slug, chapter.Title, i)
+		if err != nil {
+			return fmt.Errorf("failed to save chapter %s: %w", chapter.Slug, err)
+		}
+		for j, l := range chapter.Lessons {
+			var fetchedAt any
+			if !l.FetchedAt.IsZero() {
+				fetchedAt = formatTime(l.FetchedAt)
+			}
+			_, err := tx.Exec(`
+				INSERT INTO lessons (uuid, chapter_uuid, course_uuid, slug, title, position, content, content_hash, listing_hash, fetched_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, l.UUID, chapter.UUID, course.UUID, l.Slug, l.Title, j, l.Content, l.ContentHash, l.ListingHash, fetchedAt)
+			if err != nil {
+				return fmt.Errorf("failed to save lesson %s: %w", l.Slug, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCourse returns the local copy of a course, or nil when it was never downloaded
+func GetCourse(db *sql.DB, courseUUID string) (*Course, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	var course Course
+	err := db.QueryRow(`SELECT uuid, slug, title, description, fetched_at FROM courses WHERE uuid = ?`, courseUUID).
+		Scan(&course.UUID, &course.Slug, &course.Title, &course.Description, &course.FetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting course %s: %w", courseUUID, err)
+	}
+
+	rows, err := db.Query(`
+		SELECT c.uuid, c.slug, c.title, l.uuid, l.slug, l.title, l.content, l.content_hash, l.listing_hash, l.fetched_at
+		FROM chapters c
+		LEFT JOIN lessons l ON l.chapter_uuid = c.uuid
+		WHERE c.course_uuid = ?
+		ORDER BY c.position, l.position
+	`, courseUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting lessons of course %s: %w", courseUUID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chapter Chapter
+		var lessonUUID, slug, title, content, contentHash, listingHash sql.NullString
+		var fetchedAt sql.NullTime
+		if err := rows.Scan(&chapter.UUID, &chapter.Slug, &chapter.Title, &lessonUUID, &slug, &title, &content, &contentHash, &listingHash, &fetchedAt); err != nil {
+			return nil, fmt.Errorf("error scanning lesson: %w", err)
+		}
+		if n := len(course.Chapters); n == 0 || course.Chapters[n-1].UUID != chapter.UUID {
+			course.Chapters = append(course.Chapters, chapter)
+		}
+		if !lessonUUID.Valid {
+			continue
+		}
+		last := &course.Chapters[len(course.Chapters)-1]
+		last.Lessons = append(last.Lessons, Lesson{
+			UUID:        lessonUUID.String,
+			Slug:        slug.String,
+			Title:       title.String,
+			Content:     content.String,
+			ContentHash: contentHash.String,
+			ListingHash: listingHash.String,
+			FetchedAt:   fetchedAt.Time,
+		})
+	}
+	return &course, rows.Err()
+}
+
+// GetCourses returns the courses downloaded, without their chapters
+func GetCourses(db *sql.DB) ([]Course, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT uuid, slug, title, description, fetched_at FROM courses ORDER BY title`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting courses: %w", err)
+	}
+	defer rows.Close()
+
+	var courses []Course
+	for rows.Next() {
+		var c Course
+		if err := rows.Scan(&c.UUID, &c.Slug, &c.Title, &c.Description, &c.FetchedAt); err != nil {
+			return nil, fmt.Errorf("error scanning course: %w", err)
+		}
+		courses = append(courses, c)
+	}
+	return courses, rows.Err()
+}
+
+// GetCourseTitles returns the titles of the given courses known from the
+// downloaded courses or the cached course lists, by UUID. Unknown courses are
+// left out.
+func GetCourseTitles(db *sql.DB, courseUUIDs []string) (map[string]string, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	titles := map[string]string{}
+	if len(courseUUIDs) == 0 {
+		return titles, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(courseUUIDs)), ", ")
+	args := []any{}
+	for range 2 {
+		for _, uuid := range courseUUIDs {
+			args = append(args, uuid)
+		}
+	}
+	// The downloaded courses come first, they are the most recent
+	rows, err := db.Query(`
+		SELECT uuid, title FROM courses WHERE uuid IN (`+placeholders+`) AND title != ''
+		UNION ALL
+		SELECT uuid, title FROM user_courses WHERE uuid IN (`+placeholders+`) AND title != ''
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting course titles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uuid, title string
+		if err := rows.Scan(&uuid, &title); err != nil {
+			return nil, fmt.Errorf("error scanning course title: %w", err)
+		}
+		if _, ok := titles[uuid]; !ok {
+			titles[uuid] = title
+		}
+	}
+	return titles, rows.Err()
+}
+
+// StoredLesson is a downloaded lesson with the course and chapter it belongs to
+type StoredLesson struct {
+	Lesson
+	CourseUUID   string
+	CourseTitle  string
+	ChapterUUID  string
+	ChapterSlug  string
+	ChapterTitle string
+}
+
+// GetLesson returns a downloaded lesson by UUID or slug, or nil when it isn't
+// stored. A slug shared by several courses needs courseUUID to tell them apart.
+func GetLesson(db *sql.DB, ref, courseUUID string) (*StoredLesson, error) {
+	lessons, err := queryLessons(db, `(l.uuid = ? OR l.slug = ?) AND (? = '' OR l.course_uuid = ?)`, ref, ref, courseUUID, courseUUID)
+	if err != nil {
+		return nil, err
+	}
+	switch len(lessons) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &lessons[0], nil
+	}
+	return nil, fmt.Errorf("%d lessons are named %s, pick a course", len(lessons), ref)
+}
+
+// SearchLessons returns the downloaded lessons whose title or content contains
+// the query, ignoring case, in course order
+func SearchLessons(db *sql.DB, query, courseUUID string) ([]StoredLesson, error) {
+	pattern := "%" + escapeLike(query) + "%"
+	return queryLessons(db, `(l.title LIKE ? ESCAPE '\' OR l.content LIKE ? ESCAPE '\') AND (? = '' OR l.course_uuid = ?)`,
+		pattern, pattern, courseUUID, courseUUID)
+}
+
+func queryLessons(db *sql.DB, where string, args ...any) ([]StoredLesson, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT l.uuid, l.slug, l.title, l.content, l.content_hash, l.listing_hash, l.fetched_at,
+			co.uuid, co.title, c.uuid, c.slug, c.title
+		FROM lessons l
+		JOIN chapters c ON c.uuid = l.chapter_uuid
+		JOIN courses co ON co.uuid = l.course_uuid
+		WHERE `+where+`
+		ORDER BY co.title, c.position, l.position
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting lessons: %w", err)
+	}
+	defer rows.Close()
+
+	var lessons []StoredLesson
+	for rows.Next() {
+		var l StoredLesson
+		var fetchedAt sql.NullTime
+		err := rows.Scan(&l.UUID, &l.Slug, &l.Title, &l.Content, &l.ContentHash, &l.ListingHash, &fetchedAt,
+			&l.CourseUUID, &l.CourseTitle, &l.ChapterUUID, &l.ChapterSlug, &l.ChapterTitle)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning lesson: %w", err)
+		}
+		l.FetchedAt = fetchedAt.Time
+		lessons = append(lessons, l)
+	}
+	return lessons, rows.Err()
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// UserCourse is a course in the cached course list of a user
+type UserCourse struct {
+	UUID        string
+	Slug        string
+	Title       string
+	CompletedAt string
+}
+
+// SaveUserCourses replaces the cached course list of a user
+func SaveUserCourses(db *sql.DB, userHandle string, courses []UserCourse) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_courses WHERE user_handle = ?`, userHandle); err != nil {
+		return fmt.Errorf("failed to replace the courses of %s: %w", userHandle, err)
+	}
+	now := formatTime(time.Now())
+	for i, c := range courses {
+		_, err := tx.Exec(`
+			INSERT INTO user_courses (user_handle, uuid, slug, title, completed_at, position, cached_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (user_handle, uuid) DO NOTHING
+		`, userHandle, c.UUID, c.Slug, c.Title, c.CompletedAt, i, now)
+		if err != nil {
+			return fmt.Errorf("failed to cache course %s: %w", c.Slug, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetUserCourses returns the cached course list of a user and when it was
+// cached, or no courses when it never was
+func GetUserCourses(db *sql.DB, userHandle string) ([]UserCourse, time.Time, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT uuid, slug, title, completed_at, cached_at
+		FROM user_courses
+		WHERE user_handle = ?
+		ORDER BY position
+	`, userHandle)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error getting cached courses: %w", err)
+	}
+	defer rows.Close()
+
+	var courses []UserCourse
+	var cachedAt time.Time
+	for rows.Next() {
+		var c UserCourse
+		if err := rows.Scan(&c.UUID, &c.Slug, &c.Title, &c.CompletedAt, &cachedAt); err != nil {
+			return nil, time.Time{}, fmt.Errorf("error scanning cached course: %w", err)
+		}
+		courses = append(courses, c)
+	}
+	return courses, cachedAt, rows.Err()
+}
@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetCourseTitles(t *testing.T) {
+	db, err := InitializeDatabase(DBConfig{DBPath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stmt := range []string{
+		`INSERT INTO courses (uuid, slug, title, description, fetched_at) VALUES ('downloaded', 'd', 'Learn Go', '', '2024-01-01 00:00:00')`,
+		`INSERT INTO user_courses (user_handle, uuid, slug, title, completed_at, position, cached_at) VALUES
+			('me', 'downloaded', 'd', 'Old title', '', 0, '2024-01-01 00:00:00'),
+			('me', 'cached', 'c', 'Learn SQL', '', 1, '2024-01-01 00:00:00')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// GetCourseTitles closes the database
+	titles, err := GetCourseTitles(db, []string{"downloaded", "cached", "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"downloaded": "Learn Go", "cached": "Learn SQL"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("GetCourseTitles = %v, want %v", titles, want)
+	}
+}
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -8,36 +9,55 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/spf13/viper"
 )
 
 // DBConfig holds database configuration
 type DBConfig struct {
-	DBPath   string
-	InitFile string
+	DBPath string
 }
 
-func getDefaultDB() *sql.DB {
-	config := DBConfig{
-		DBPath:   "data/bootdev.db",
-		InitFile: "init.sql",
+// DefaultDBPath returns $XDG_DATA_HOME/bootdev/bootdev.db, falling back to
+// ~/.local/share/bootdev/bootdev.db when XDG_DATA_HOME is not set
+func DefaultDBPath() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "bootdev", "bootdev.db")
+}
+
+// DefaultDBConfig returns the database location set with the db_path config
+// key (or the --db flag), or the default one
+func DefaultDBConfig() DBConfig {
+	dbPath := viper.GetString("db_path")
+	if dbPath == "" {
+		return DBConfig{DBPath: DefaultDBPath()}
+	}
+	if rest, ok := strings.CutPrefix(dbPath, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			dbPath = filepath.Join(home, rest)
+		}
 	}
-	db, err := InitializeDatabase(config)
+	return DBConfig{DBPath: dbPath}
+}
+
+func getDefaultDB() *sql.DB {
+	db, err := InitializeDatabase(DefaultDBConfig())
 	if err != nil {
 		panic(err)
 	}
 	return db
 }
 
-// InitializeDatabase creates and initializes the SQLite database if it doesn't exist
-func InitializeDatabase(config DBConfig) (*sql.DB, error) {
-	// Check if database file already exists
-	dbExists := true
-	if _, err := os.Stat(config.DBPath); os.IsNotExist(err) {
-		dbExists = false
-	}
-
+func openDatabase(config DBConfig) (*sql.DB, error) {
 	// Create directory if it doesn't exist
 	dbDir := filepath.Dir(config.DBPath)
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
@@ -55,77 +75,83 @@ func InitializeDatabase(config DBConfig) (*sql.DB, error) {
 		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
+	return db, nil
+}
 
-	// If database didn't exist or is empty, initialize it
-	if !dbExists || isDatabaseEmpty(db) {
-		if err := initializeSchema(db, config.InitFile); err != nil {
-			db.Close()
-			return nil, fmt.Errorf("failed to initialize database schema: %w", err)
-		}
+// InitializeDatabase opens the SQLite database, creating it if it doesn't
+// exist, and applies any pending migrations
+func InitializeDatabase(config DBConfig) (*sql.DB, error) {
+	db, err := openDatabase(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := MigrateUp(db, 0); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
 	}
 	return db, nil
 }
 
+// OpenDatabase opens the SQLite database without applying migrations
+func OpenDatabase(config DBConfig) (*sql.DB, error) {
+	return openDatabase(config)
+}
+
 // ResetDatabase drops all tables and recreates them with fresh data
 func ResetDatabase(config DBConfig) error {
-	// Open database connection
-	db, err := sql.Open("sqlite3", config.DBPath+"?_foreign_keys=on")
+	db, err := openDatabase(config)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 
-	// Test the connection
-	if err := db.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	// Drop all tables
-	dropQueries := []string{
-		"DROP TABLE IF EXISTS user_answers",
-		"DROP TABLE IF EXISTS questions",
-		"DROP TABLE IF EXISTS quizzes",
-		"DROP TABLE IF EXISTS question_types",
-	}
-
-	for _, query := range dropQueries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("failed to drop table: %w", err)
-		}
+	if err := dropAllTables(db); err != nil {
+		return err
 	}
 
 	// Recreate schema
-	if err := initializeSchema(db, config.InitFile); err != nil {
+	if _, err := MigrateUp(db, 0); err != nil {
 		return fmt.Errorf("failed to recreate schema: %w", err)
 	}
 
 	return nil
 }
 
-// isDatabaseEmpty checks if the database has any tables
-func isDatabaseEmpty(db *sql.DB) bool {
-	var count int
-	query := `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'`
-	err := db.QueryRow(query).Scan(&count)
+// dropAllTables drops every table, including schema_migrations
+func dropAllTables(db *sql.DB) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
 	if err != nil {
-		return true // Assume empty if we can't check
+		return fmt.Errorf("failed to get a connection: %w", err)
 	}
-	return count == 0
-}
+	defer conn.Close()
 
-// initializeSchema reads and executes the SQL initialization file
-func initializeSchema(db *sql.DB, initFile string) error {
-	// Read the SQL file
-	sqlContent, err := os.ReadFile(initFile)
-	if err != nil {
-		return fmt.Errorf("failed to read init file %s: %w", initFile, err)
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
 	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
 
-	// Execute the SQL content
-	if _, err := db.Exec(string(sqlContent)); err != nil {
-		return fmt.Errorf("failed to execute initialization SQL: %w", err)
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
 	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list tables: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
 
+	for _, table := range tables {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %q", table)); err != nil {
+			return fmt.Errorf("failed to drop table: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -140,19 +166,20 @@ func CreateQuiz(db *sql.DB, quiz *Quiz) error {
 	if err != nil {
 		return err
 	}
-	insertQuiz := `INSERT INTO quizzes (course_uuid) VALUES (?) ON CONFLICT (course_uuid) DO NOTHING`
+	insertQuiz := `INSERT INTO quizzes (course_uuid, deck) VALUES (?, ?) ON CONFLICT (course_uuid, deck) DO NOTHING`
 	insertQuestion := `
-	INSERT INTO questions (quiz_id, question_type_id, question_text, explanation, answer_choices, correct_answer) 
-	VALUES ((select id from quizzes where course_uuid = ?), (select id from question_types where name = ?),  ?, ?, ?, ?)
+	INSERT INTO questions (quiz_id, question_type_id, question_text, explanation, answer_choices, correct_answer, accepted_answers, rubric, lesson_uuid, lesson_slug, chapter_uuid, chapter_slug, generation_run_id) 
+	VALUES ((select id from quizzes where course_uuid = ? and deck = ?), (select id from question_types where name = ?),  ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0))
 	`
 
-	_, err = tx.Exec(insertQuiz, quiz.CourseUUID)
+	deck := quiz.GetDeck()
+	_, err = tx.Exec(insertQuiz, quiz.CourseUUID, deck)
 	if err != nil {
 		return err
 	}
 
 	for _, q := range quiz.Questions {
-		_, err := tx.Exec(insertQuestion, quiz.CourseUUID, q.QuestionType, q.QuestionText, q.Explanation, q.AnswerChoices, q.CorrectAnswer)
+		_, err := tx.Exec(insertQuestion, quiz.CourseUUID, deck, q.QuestionType, q.QuestionText, q.Explanation, q.AnswerChoices, q.CorrectAnswer, q.AcceptedAnswers, q.Rubric, q.LessonUUID, q.LessonSlug, q.ChapterUUID, q.ChapterSlug, q.GenerationRunID)
 		if err != nil {
 			return err
 		}
@@ -163,6 +190,7 @@ func CreateQuiz(db *sql.DB, quiz *Quiz) error {
 // QuizStats holds statistics for a specific quiz/course
 type QuizStats struct {
 	CourseUUID      string
+	Deck            string
 	QuestionCount   int
 	TotalAnswers    int
 	CorrectAnswers  int
@@ -174,14 +202,15 @@ func GetQuizzesStats(db *sql.DB) ([]QuizStats, error) {
 	query := `
 		SELECT 
 			qz.course_uuid,
+			qz.deck,
 			COUNT(DISTINCT q.id) as question_count,
 			COUNT(ua.id) as total_answers,
 			SUM(CASE WHEN ua.is_correct = 1 THEN 1 ELSE 0 END) as correct_answers
 		FROM quizzes qz
 		LEFT JOIN questions q ON qz.id = q.quiz_id
 		LEFT JOIN user_answers ua ON q.id = ua.question_id
-		GROUP BY qz.course_uuid
-		ORDER BY qz.course_uuid
+		GROUP BY qz.course_uuid, qz.deck
+		ORDER BY qz.course_uuid, qz.deck
 	`
 
 	rows, err := db.Query(query)
@@ -193,7 +222,7 @@ func GetQuizzesStats(db *sql.DB) ([]QuizStats, error) {
 	var stats []QuizStats
 	for rows.Next() {
 		var stat QuizStats
-		err := rows.Scan(&stat.CourseUUID, &stat.QuestionCount, &stat.TotalAnswers, &stat.CorrectAnswers)
+		err := rows.Scan(&stat.CourseUUID, &stat.Deck, &stat.QuestionCount, &stat.TotalAnswers, &stat.CorrectAnswers)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning quiz stats: %w", err)
 		}
@@ -240,11 +269,30 @@ func GetQuizStats(db *sql.DB, courseUUID string) (*QuizStats, error) {
 type Question struct {
 	ID            int64
 	QuizID        int64
+	CourseUUID    string
 	QuestionType  string
 	QuestionText  string
 	Explanation   string
 	AnswerChoices string
 	CorrectAnswer string
+	// AcceptedAnswers is a JSON array of alternative answers for fill_blank questions
+	AcceptedAnswers string
+	// Rubric describes how free_response answers are graded against CorrectAnswer
+	Rubric string
+	// The lesson the question was generated from, and its chapter, if known
+	LessonUUID  string
+	LessonSlug  string
+	ChapterUUID string
+	ChapterSlug string
+	// Archived questions are left out of quizzes and reviews
+	Archived bool
+	// FlagReason is set when the question was flagged during a quiz, which
+	// also leaves it out until it is fixed
+	FlagReason string
+	FlagNote   string
+	// GenerationRunID is the call to a language model the question was
+	// generated by, when known. Only written when the question is created.
+	GenerationRunID int64
 }
 
 func shuffle[T any](target []T) {
@@ -253,9 +301,22 @@ func shuffle[T any](target []T) {
 	})
 }
 
+// QuestionTypes are the names of every question type in the question_types table
+var QuestionTypes = []string{"multiple_choice", "fill_blank", "free_response", "multi_select", "ordering"}
+
+// HasChoices reports whether the question is answered by picking from (or ordering) AnswerChoices
+func (q *Question) HasChoices() bool {
+	switch q.QuestionType {
+	case "multiple_choice", "multi_select", "ordering":
+		return true
+	default:
+		return false
+	}
+}
+
 func (q *Question) GetAnswerChoices() []string {
 	choices := []string{}
-	if q.QuestionType != "multiple_choice" {
+	if !q.HasChoices() {
 		return choices
 	} else {
 		json.Unmarshal([]byte(q.AnswerChoices), &choices)
@@ -263,22 +324,62 @@ func (q *Question) GetAnswerChoices() []string {
 	return choices
 }
 
+// GetCorrectAnswers returns the set of correct choices of a multi_select question,
+// the items in their correct order for an ordering question, or the single
+// correct answer of any other question
+func (q *Question) GetCorrectAnswers() []string {
+	if q.QuestionType != "multi_select" && q.QuestionType != "ordering" {
+		return []string{q.CorrectAnswer}
+	}
+	answers := []string{}
+	json.Unmarshal([]byte(q.CorrectAnswer), &answers)
+	return answers
+}
+
+// IsCorrectChoice reports whether a choice is (one of) the correct answer(s)
+func (q *Question) IsCorrectChoice(choice string) bool {
+	for _, answer := range q.GetCorrectAnswers() {
+		if strings.TrimSpace(choice) == strings.TrimSpace(answer) {
+			return true
+		}
+	}
+	return false
+}
+
 func (q *Question) IsCorrectAnswer(answer string) bool {
 	return strings.TrimSpace(answer) == strings.TrimSpace(q.CorrectAnswer)
 }
 
+// DefaultDeck holds the questions of a course that were not put in a named deck
+const DefaultDeck = "default"
+
 type Quiz struct {
 	CourseUUID string
-	Questions  []Question
+	// Deck is the name of the quiz among the quizzes of the course
+	Deck      string
+	Questions []Question
+}
+
+// GetDeck returns the deck name, DefaultDeck if it is not set
+func (q *Quiz) GetDeck() string {
+	if q.Deck == "" {
+		return DefaultDeck
+	}
+	return q.Deck
 }
 
 func (q *Quiz) ShuffleQuestions() {
 	shuffle(q.Questions)
-	
+
 	// Also shuffle answers within each question
+	q.ShuffleChoices()
+}
+
+// ShuffleChoices shuffles the answers within each question, keeping the question order
+func (q *Quiz) ShuffleChoices() {
 	for i := range q.Questions {
 		question := &q.Questions[i]
-		if question.QuestionType == "multiple_choice" {
+		if question.HasChoices() {
 			choices := []string{}
 			json.Unmarshal([]byte(question.AnswerChoices), &choices)
 			if len(choices) > 1 {
@@ -290,70 +391,199 @@ func (q *Quiz) ShuffleQuestions() {
 	}
 }
 
+// QuestionFilter narrows questions down to a course, deck, chapter or lesson.
+// Empty fields match everything.
+type QuestionFilter struct {
+	CourseUUID string
+	Deck       string
+	// Chapter and Lesson match either the UUID or the slug
+	Chapter string
+	Lesson  string
+	// IncludeArchived also returns the archived questions
+	IncludeArchived bool
+	// IncludeFlagged also returns the flagged questions, Flagged only them
+	IncludeFlagged bool
+	Flagged        bool
+}
+
+// where returns the SQL condition selecting the filtered questions, q and qz
+// being questions and quizzes
+func (f QuestionFilter) where() (string, []any) {
+	condition := `(? = '' OR qz.course_uuid = ?)
+		  AND (? = '' OR qz.deck = ?)
+		  AND (? = '' OR q.chapter_uuid = ? OR q.chapter_slug = ?)
+		  AND (? = '' OR q.lesson_uuid = ? OR q.lesson_slug = ?)
+		  AND (? OR q.archived_at IS NULL)
+		  AND (? OR q.flagged_at IS NULL)
+		  AND (NOT ? OR q.flagged_at IS NOT NULL)`
+	args := []any{
+		f.CourseUUID, f.CourseUUID,
+		f.Deck, f.Deck,
+		f.Chapter, f.Chapter, f.Chapter,
+		f.Lesson, f.Lesson, f.Lesson,
+		f.IncludeArchived,
+		f.IncludeFlagged || f.Flagged,
+		f.Flagged,
+	}
+	return condition, args
+}
+
+// GetQuiz returns the due questions of every deck of a course, or nil if none is due
 func GetQuiz(db *sql.DB, courseUUID string) (*Quiz, error) {
+	return GetFilteredQuiz(db, QuestionFilter{CourseUUID: courseUUID})
+}
+
+// GetFilteredQuiz returns the due questions matching the filter, or nil if none is due
+func GetFilteredQuiz(db *sql.DB, filter QuestionFilter) (*Quiz, error) {
 	if db == nil {
 		db = getDefaultDB()
 	}
 	defer db.Close()
 
+	questions, err := getDueQuestions(db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(questions) > 0 {
+		return &Quiz{CourseUUID: filter.CourseUUID, Deck: filter.Deck, Questions: questions}, nil
+	} else {
+		return nil, nil
+	}
+}
+
+// questionColumns are the columns read by scanQuestion, q, qz and qt being
+// questions, quizzes and question_types
+const questionColumns = `q.id, q.quiz_id, qz.course_uuid, qt.name, q.question_text, q.explanation, q.answer_choices, q.correct_answer, COALESCE(q.accepted_answers, ''), COALESCE(q.rubric, ''),
+	COALESCE(q.lesson_uuid, ''), COALESCE(q.lesson_slug, ''), COALESCE(q.chapter_uuid, ''), COALESCE(q.chapter_slug, ''), q.archived_at IS NOT NULL,
+	COALESCE(q.flag_reason, ''), COALESCE(q.flag_note, '')`
+
+func scanQuestion(rows *sql.Rows, extra ...any) (Question, error) {
+	var question Question
+	dest := []any{
+		&question.ID,
+		&question.QuizID,
+		&question.CourseUUID,
+		&question.QuestionType,
+		&question.QuestionText,
+		&question.Explanation,
+		&question.AnswerChoices,
+		&question.CorrectAnswer,
+		&question.AcceptedAnswers,
+		&question.Rubric,
+		&question.LessonUUID,
+		&question.LessonSlug,
+		&question.ChapterUUID,
+		&question.ChapterSlug,
+		&question.Archived,
+		&question.FlagReason,
+		&question.FlagNote,
+	}
+	err := rows.Scan(append(dest, extra...)...)
+	return question, err
+}
+
+// GetQuestions returns every question matching the filter, due or not
+func GetQuestions(db *sql.DB, filter QuestionFilter) ([]Question, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	return getQuestions(db, filter)
+}
+
+func getQuestions(db *sql.DB, filter QuestionFilter) ([]Question, error) {
+	where, args := filter.where()
 	query := `
-		SELECT q.id, q.quiz_id, qt.name, q.question_text, q.explanation, q.answer_choices, q.correct_answer
+		SELECT ` + questionColumns + `
 		FROM questions q
 		JOIN quizzes qz ON q.quiz_id = qz.id
 		JOIN question_types qt ON q.question_type_id = qt.id
-		WHERE qz.course_uuid = ? 
-		  AND qt.name = 'multiple_choice'
-		  AND q.id NOT IN (
-			  SELECT question_id FROM user_answers WHERE question_id = q.id AND is_correct = true
-		  )
+		WHERE ` + where + `
 		ORDER BY q.id ASC
 	`
 
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting questions: %w", err)
+	}
+	defer rows.Close()
+
+	questions := []Question{}
+	for rows.Next() {
+		question, err := scanQuestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error getting a quiz question: %w", err)
+		}
+		questions = append(questions, question)
+	}
+	return questions, rows.Err()
+}
+
+// getDueQuestions returns the questions matching the filter that are due for review
+func getDueQuestions(db *sql.DB, filter QuestionFilter) ([]Question, error) {
+	where, filterArgs := filter.where()
+	query := `
+		SELECT ` + questionColumns + `, s.due_at IS NULL
+		FROM questions q
+		JOIN quizzes qz ON q.quiz_id = qz.id
+		JOIN question_types qt ON q.question_type_id = qt.id
+		LEFT JOIN question_schedules s ON q.id = s.question_id AND s.scheduler = ?
+		WHERE ` + where + `
+		  AND (s.due_at IS NULL OR s.due_at <= ?)
+		ORDER BY q.id ASC
+	`
+
+	scheduler, err := ActiveScheduler()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
 	questions := []Question{}
+	unscheduled := map[int64]bool{}
 
-	rows, err := db.Query(query, courseUUID)
+	args := append([]any{scheduler.Name()}, filterArgs...)
+	rows, err := db.Query(query, append(args, now.Format(sqliteTimeFormat))...)
 	if err != nil {
 		return nil, fmt.Errorf("error getting quiz: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var question Question
-		err := rows.Scan(
-			&question.ID,
-			&question.QuizID,
-			&question.QuestionType,
-			&question.QuestionText,
-			&question.Explanation,
-			&question.AnswerChoices,
-			&question.CorrectAnswer,
-		)
+		var isUnscheduled bool
+		question, err := scanQuestion(rows, &isUnscheduled)
 		if err != nil {
 			return nil, fmt.Errorf("error getting a quiz question: %w", err)
 		}
 		questions = append(questions, question)
+		unscheduled[question.ID] = isUnscheduled
 	}
+	rows.Close()
 
-	if len(questions) > 0 {
-		return &Quiz{CourseUUID: courseUUID, Questions: questions}, nil
-	} else {
-		return nil, nil
-	}
+	return filterDue(db, scheduler, questions, unscheduled, now)
 }
 
-func MarkQuestionAnswered(db *sql.DB, questionId int, answer string, isCorrect bool) error {
+// MarkQuestionAnswered stores an answer along with its grade
+func MarkQuestionAnswered(db *sql.DB, questionId int, answer string, grade Grade) error {
 	stmt := `
-	insert into user_answers(question_id, user_answer, is_correct) values (?, ?, ?) 
+	insert into user_answers(question_id, user_answer, is_correct, score, feedback, scheduler) values (?, ?, ?, ?, ?, ?) 
 	`
 
+	scheduler, err := ActiveScheduler()
+	if err != nil {
+		return err
+	}
+
 	if db == nil {
 		db = getDefaultDB()
 	}
 	defer db.Close()
 
-	_, err := db.Exec(stmt, questionId, answer, isCorrect)
+	_, err = db.Exec(stmt, questionId, answer, grade.Correct, grade.Score, grade.Feedback, scheduler.Name())
 	if err != nil {
-		panic(fmt.Sprintf("%w", questionId, err))
+		return fmt.Errorf("error saving answer for question %d: %w", questionId, err)
 	}
-	return err
+	return nil
 }
@@ -0,0 +1,100 @@
+package dao
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultDuplicateThreshold is the Similarity from which a generated question
+// is considered a near duplicate of another one. It is above the weight of the
+// text, so the same text asking for an unrelated answer is kept: a duplicate
+// needs some of its answer in common too.
+const DefaultDuplicateThreshold = 0.75
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// stopWords are too common to tell two questions apart
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "be": true, "by": true,
+	"do": true, "does": true, "for": true, "how": true, "in": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "the": true, "this": true, "that": true, "to": true,
+	"what": true, "when": true, "which": true, "why": true, "with": true,
+}
+
+// Duplicate is a question left out because it is too similar to another one
+type Duplicate struct {
+	Question   Question
+	Of         Question
+	Similarity float64
+}
+
+// shingles returns the words of a text and the pairs of consecutive words,
+// lowercased and without stop words
+func shingles(text string) map[string]bool {
+	words := []string{}
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		if !stopWords[word] {
+			words = append(words, word)
+		}
+	}
+
+	set := map[string]bool{}
+	for i, word := range words {
+		set[word] = true
+		if i > 0 {
+			set[words[i-1]+" "+word] = true
+		}
+	}
+	return set
+}
+
+// jaccard is the size of the intersection of two sets over the size of their union
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for s := range a {
+		if b[s] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Similarity compares the texts and the correct answers of two questions, from
+// 0 for nothing in common to 1 for the same wording. The text weighs 0.7 and
+// the answers 0.3, so the same text with an unrelated answer scores 0.7.
+func Similarity(a, b *Question) float64 {
+	if a.ContentHash() == b.ContentHash() {
+		return 1
+	}
+	text := jaccard(shingles(a.QuestionText), shingles(b.QuestionText))
+	answers := jaccard(
+		shingles(strings.Join(a.GetCorrectAnswers(), " ")),
+		shingles(strings.Join(b.GetCorrectAnswers(), " ")),
+	)
+	return 0.7*text + 0.3*answers
+}
+
+// RemoveDuplicates drops the questions whose Similarity to an existing question,
+// or to a question kept before them, reaches the threshold. A threshold above 1
+// keeps every question.
+func RemoveDuplicates(existing, questions []Question, threshold float64) ([]Question, []Duplicate) {
+	kept := []Question{}
+	duplicates := []Duplicate{}
+	for _, q := range questions {
+		best := Duplicate{Similarity: -1}
+		for _, other := range append(existing[:len(existing):len(existing)], kept...) {
+			if similarity := Similarity(&q, &other); similarity > best.Similarity {
+				best = Duplicate{Question: q, Of: other, Similarity: similarity}
+			}
+		}
+		if best.Similarity >= threshold {
+			duplicates = append(duplicates, best)
+		} else {
+			kept = append(kept, q)
+		}
+	}
+	return kept, duplicates
+}
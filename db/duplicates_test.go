@@ -0,0 +1,141 @@
+package dao
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestShingles(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"What is the?", []string{}},
+		{"Binary search", []string{"binary", "binary search", "search"}},
+		// Case, punctuation and stop words are dropped before pairing words
+		{"What is the Big-O of a BINARY search?", []string{"big", "big o", "binary", "binary search", "o", "o binary", "search"}},
+		{"Go: go go", []string{"go", "go go"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for s := range shingles(tt.text) {
+			got = append(got, s)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shingles(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	set := func(items ...string) map[string]bool {
+		s := map[string]bool{}
+		for _, item := range items {
+			s[item] = true
+		}
+		return s
+	}
+	tests := []struct {
+		a, b map[string]bool
+		want float64
+	}{
+		{set(), set(), 1},
+		{set("a"), set(), 0},
+		{set("a", "b"), set("c", "d"), 0},
+		{set("a", "b"), set("b", "c"), 1.0 / 3},
+		{set("a", "b", "c"), set("a", "b", "c"), 1},
+	}
+	for _, tt := range tests {
+		if got := jaccard(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("jaccard(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func fillBlank(text, answer string) Question {
+	return Question{QuestionType: "fill_blank", QuestionText: text, CorrectAnswer: answer}
+}
+
+func TestSimilarity(t *testing.T) {
+	base := fillBlank("What does the len function return for a slice?", "The number of elements")
+	tests := []struct {
+		name      string
+		other     Question
+		duplicate bool
+	}{
+		{"same question", base, true},
+		{"other case and spacing", fillBlank("what does the LEN function  return for a slice?", "the number of elements"), true},
+		{"reworded", fillBlank("In Go, what does the len function return for a slice?", "The number of elements"), true},
+		{"same text, unrelated answer", fillBlank("What does the len function return for a slice?", "Its capacity"), false},
+		{"unrelated", fillBlank("Which keyword starts a goroutine?", "go"), false},
+	}
+	for _, tt := range tests {
+		similarity := Similarity(&base, &tt.other)
+		if similarity < 0 || similarity > 1 {
+			t.Errorf("%s: Similarity = %v, want it in [0, 1]", tt.name, similarity)
+		}
+		if duplicate := similarity >= DefaultDuplicateThreshold; duplicate != tt.duplicate {
+			t.Errorf("%s: Similarity = %v, duplicate %v; want %v", tt.name, similarity, duplicate, tt.duplicate)
+		}
+	}
+
+	unrelatedAnswer := fillBlank(base.QuestionText, "Its capacity")
+	if got := Similarity(&base, &unrelatedAnswer); math.Abs(got-0.7) > 1e-9 {
+		t.Errorf("Similarity with an unrelated answer = %v, want the weight of the text, 0.7", got)
+	}
+}
+
+func TestRemoveDuplicates(t *testing.T) {
+	existing := []Question{
+		fillBlank("Which keyword starts a goroutine?", "go"),
+		fillBlank("What does the len function return for a slice?", "The number of elements"),
+	}
+	questions := []Question{
+		fillBlank("Which KEYWORD starts a goroutine?", "go"),
+		fillBlank("What does the cap function return for a slice?", "The size of its backing array"),
+		fillBlank("What does the len function return for a slice?", "Its capacity"),
+		fillBlank("In Go, what does the cap function return for a slice?", "The size of its backing array"),
+		fillBlank("Which package formats text?", "fmt"),
+	}
+	// Room to grow must not let the kept questions overwrite existing ones
+	existing = append(make([]Question, 0, 10), existing...)
+
+	kept, duplicates := RemoveDuplicates(existing, questions, DefaultDuplicateThreshold)
+
+	wantKept := []string{questions[1].QuestionText, questions[2].QuestionText, questions[4].QuestionText}
+	gotKept := []string{}
+	for _, q := range kept {
+		gotKept = append(gotKept, q.QuestionText)
+	}
+	if !reflect.DeepEqual(gotKept, wantKept) {
+		t.Errorf("kept %q, want %q", gotKept, wantKept)
+	}
+
+	if len(duplicates) != 2 {
+		t.Fatalf("got %d duplicates, want 2: %+v", len(duplicates), duplicates)
+	}
+	// A duplicate of an existing question
+	if d := duplicates[0]; d.Question.QuestionText != questions[0].QuestionText || d.Of.QuestionText != existing[0].QuestionText || d.Similarity != 1 {
+		t.Errorf("first duplicate = %q of %q (%v), want %q of the existing %q", d.Question.QuestionText, d.Of.QuestionText, d.Similarity, questions[0].QuestionText, existing[0].QuestionText)
+	}
+	// A duplicate of a question kept before it
+	if d := duplicates[1]; d.Question.QuestionText != questions[3].QuestionText || d.Of.QuestionText != questions[1].QuestionText {
+		t.Errorf("second duplicate = %q of %q, want %q of %q", d.Question.QuestionText, d.Of.QuestionText, questions[3].QuestionText, questions[1].QuestionText)
+	}
+
+	if len(existing) != 2 || existing[:cap(existing)][2].QuestionText != "" {
+		t.Errorf("existing questions were modified: %+v", existing[:cap(existing)][:3])
+	}
+}
+
+func TestRemoveDuplicatesAboveOneKeepsAll(t *testing.T) {
+	q := fillBlank("Which keyword starts a goroutine?", "go")
+	kept, duplicates := RemoveDuplicates([]Question{q}, []Question{q, q}, 1.01)
+	if len(kept) != 2 || len(duplicates) != 0 {
+		t.Errorf("kept %d and dropped %d, want every question kept", len(kept), len(duplicates))
+	}
+}
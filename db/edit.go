@@ -0,0 +1,131 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ToFile returns the fields of a question in the quiz file layout, which is
+// also how they are edited
+func (q *Question) ToFile() QuestionFile {
+	return questionToFile(*q)
+}
+
+// UpdateQuestion replaces the content of a question with the edited fields,
+// which may change its type. Its answer history and schedules are kept, and
+// its flag is cleared since the question was fixed.
+func UpdateQuestion(db *sql.DB, id int64, f QuestionFile) error {
+	return saveQuestion(db, id, f, false)
+}
+
+// ReplaceQuestion puts a new question in place of an existing one in the same
+// deck, clearing its flag and dropping its answer history and schedules
+func ReplaceQuestion(db *sql.DB, id int64, f QuestionFile) error {
+	return saveQuestion(db, id, f, true)
+}
+
+func saveQuestion(db *sql.DB, id int64, f QuestionFile, dropHistory bool) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	q, err := f.toQuestion("")
+	if err != nil {
+		return err
+	}
+	q.ID = id
+
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateQuestion(tx, q); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE questions SET flag_reason = NULL, flag_note = NULL, flagged_at = NULL WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to clear the flag of question %d: %w", id, err)
+	}
+	if dropHistory {
+		for _, table := range []string{"user_answers", "question_schedules"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE question_id = ?`, id); err != nil {
+				return fmt.Errorf("failed to reset the history of question %d: %w", id, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteQuestion removes a question along with its answers and schedules
+func DeleteQuestion(db *sql.DB, id int64) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`DELETE FROM questions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete question %d: %w", id, err)
+	}
+	return nil
+}
+
+// ArchiveQuestion takes a question out of quizzes and reviews without losing
+// its history, or brings it back when archived is false
+func ArchiveQuestion(db *sql.DB, id int64, archived bool) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	var archivedAt any
+	if archived {
+		archivedAt = time.Now().UTC().Format(sqliteTimeFormat)
+	}
+	if _, err := db.Exec(`UPDATE questions SET archived_at = ? WHERE id = ?`, archivedAt, id); err != nil {
+		return fmt.Errorf("failed to archive question %d: %w", id, err)
+	}
+	return nil
+}
+
+// FlagReasons are the reasons a question can be flagged for during a quiz
+var FlagReasons = []string{"wrong", "ambiguous", "too_easy"}
+
+// FlagQuestion takes a question out of quizzes and reviews until it is fixed,
+// regenerated or unflagged
+func FlagQuestion(db *sql.DB, id int64, reason, note string) error {
+	if !slices.Contains(FlagReasons, reason) {
+		return fmt.Errorf("unknown flag reason %q (expected one of %v)", reason, FlagReasons)
+	}
+
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	stmt := `UPDATE questions SET flag_reason = ?, flag_note = NULLIF(?, ''), flagged_at = ? WHERE id = ?`
+	if _, err := db.Exec(stmt, reason, strings.TrimSpace(note), time.Now().UTC().Format(sqliteTimeFormat), id); err != nil {
+		return fmt.Errorf("failed to flag question %d: %w", id, err)
+	}
+	return nil
+}
+
+// UnflagQuestion puts a flagged question back into quizzes and reviews as it is
+func UnflagQuestion(db *sql.DB, id int64) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`UPDATE questions SET flag_reason = NULL, flag_note = NULL, flagged_at = NULL WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to unflag question %d: %w", id, err)
+	}
+	return nil
+}
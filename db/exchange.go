@@ -0,0 +1,528 @@
+package dao
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// QuizFileVersion is the version of the quiz file format written by ExportQuiz.
+// The format is documented in docs/quiz-format.md. Version 2 added decks and
+// the lesson a question comes from.
+const QuizFileVersion = 2
+
+// QuizFile is the portable representation of a quiz, used to share
+// questions written by hand or generated on another machine
+type QuizFile struct {
+	Version    int            `json:"version" yaml:"version"`
+	CourseUUID string         `json:"course_uuid" yaml:"course_uuid"`
+	Deck       string         `json:"deck,omitempty" yaml:"deck,omitempty"`
+	ExportedAt time.Time      `json:"exported_at,omitempty" yaml:"exported_at,omitempty"`
+	Questions  []QuestionFile `json:"questions" yaml:"questions"`
+}
+
+// QuestionFile is a question in a QuizFile. Which fields are used depends on the type:
+//   - multiple_choice: choices, answer
+//   - fill_blank: answer, accepted_answers
+//   - free_response: answer (the reference answer), rubric
+//   - multi_select: choices, answers (the correct choices)
+//   - ordering: answers (the items in the correct order)
+type QuestionFile struct {
+	Type            string       `json:"type" yaml:"type"`
+	Question        string       `json:"question" yaml:"question"`
+	Choices         []string     `json:"choices,omitempty" yaml:"choices,omitempty"`
+	Answer          string       `json:"answer,omitempty" yaml:"answer,omitempty"`
+	Answers         []string     `json:"answers,omitempty" yaml:"answers,omitempty"`
+	AcceptedAnswers []string     `json:"accepted_answers,omitempty" yaml:"accepted_answers,omitempty"`
+	Explanation     string       `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	Rubric          string       `json:"rubric,omitempty" yaml:"rubric,omitempty"`
+	LessonUUID      string       `json:"lesson_uuid,omitempty" yaml:"lesson_uuid,omitempty"`
+	LessonSlug      string       `json:"lesson_slug,omitempty" yaml:"lesson_slug,omitempty"`
+	ChapterUUID     string       `json:"chapter_uuid,omitempty" yaml:"chapter_uuid,omitempty"`
+	ChapterSlug     string       `json:"chapter_slug,omitempty" yaml:"chapter_slug,omitempty"`
+	History         []AnswerFile `json:"history,omitempty" yaml:"history,omitempty"`
+}
+
+// AnswerFile is a past answer to a question in a QuizFile
+type AnswerFile struct {
+	Answer     string    `json:"answer" yaml:"answer"`
+	Correct    bool      `json:"correct" yaml:"correct"`
+	Score      *float64  `json:"score,omitempty" yaml:"score,omitempty"`
+	Feedback   string    `json:"feedback,omitempty" yaml:"feedback,omitempty"`
+	Scheduler  string    `json:"scheduler,omitempty" yaml:"scheduler,omitempty"`
+	AnsweredAt time.Time `json:"answered_at" yaml:"answered_at"`
+}
+
+// ImportResult counts what an import changed
+type ImportResult struct {
+	Added        int
+	Updated      int
+	Unchanged    int
+	AnswersAdded int
+}
+
+// QuizFileFormat guesses the format of a quiz file from its extension, "json",
+// "apkg" (an Anki deck), "md" or "yaml"
+func QuizFileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".apkg":
+		return "apkg"
+	case ".md":
+		return "md"
+	default:
+		return "yaml"
+	}
+}
+
+// EncodeQuizFile writes a quiz file in the given format, "json", "yaml", "apkg"
+// or "md". Anki decks and Markdown do not keep the answer history, and Markdown
+// only has the MarkdownQuestionTypes.
+func EncodeQuizFile(w io.Writer, f *QuizFile, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(f)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "apkg":
+		return writeAnkiPackage(w, f)
+	case "md":
+		return writeMarkdown(w, f)
+	default:
+		return fmt.Errorf("unknown quiz file format %q (available: json, yaml, apkg, md)", format)
+	}
+}
+
+// DecodeQuizFile reads and validates a quiz file in the given format
+func DecodeQuizFile(r io.Reader, format string) (*QuizFile, error) {
+	var f QuizFile
+	var err error
+	switch format {
+	case "json":
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	case "yaml":
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	case "apkg":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		deck, err := readAnkiPackage(data)
+		if err != nil {
+			return nil, err
+		}
+		f = *deck
+	case "md":
+		var md *QuizFile
+		if md, err = readMarkdown(r); err != nil {
+			return nil, err
+		}
+		f = *md
+	default:
+		return nil, fmt.Errorf("unknown quiz file format %q (available: json, yaml, apkg, md)", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse quiz file: %w", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// GetDeck returns the deck name, DefaultDeck if it is not set
+func (f *QuizFile) GetDeck() string {
+	if f.Deck == "" {
+		return DefaultDeck
+	}
+	return f.Deck
+}
+
+// Validate checks that the file can be imported, reporting every invalid question
+func (f *QuizFile) Validate() error {
+	if f.Version < 1 || f.Version > QuizFileVersion {
+		return fmt.Errorf("unsupported quiz file version %d (supported: 1 to %d)", f.Version, QuizFileVersion)
+	}
+	if strings.TrimSpace(f.CourseUUID) == "" {
+		return fmt.Errorf("course_uuid is required")
+	}
+
+	var errs []error
+	for i, q := range f.Questions {
+		if err := q.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("question %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that a question has what its type needs to be asked and graded
+func (q *QuestionFile) Validate() error {
+	if strings.TrimSpace(q.Question) == "" {
+		return fmt.Errorf("question text is required")
+	}
+	switch q.Type {
+	case "multiple_choice":
+		if len(q.Choices) < 2 {
+			return fmt.Errorf("multiple_choice needs at least 2 choices")
+		}
+		if !containsTrimmed(q.Choices, q.Answer) {
+			return fmt.Errorf("answer %q is not one of the choices", q.Answer)
+		}
+	case "fill_blank", "free_response":
+		if strings.TrimSpace(q.Answer) == "" {
+			return fmt.Errorf("%s needs an answer", q.Type)
+		}
+	case "multi_select":
+		if len(q.Choices) < 2 || len(q.Answers) == 0 {
+			return fmt.Errorf("multi_select needs at least 2 choices and 1 correct answer")
+		}
+		for _, answer := range q.Answers {
+			if !containsTrimmed(q.Choices, answer) {
+				return fmt.Errorf("answer %q is not one of the choices", answer)
+			}
+		}
+	case "ordering":
+		if len(q.Answers) < 2 {
+			return fmt.Errorf("ordering needs at least 2 items in answers")
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", q.Type)
+	}
+	for i, a := range q.History {
+		if a.AnsweredAt.IsZero() {
+			return fmt.Errorf("history entry %d has no answered_at", i+1)
+		}
+	}
+	return nil
+}
+
+func containsTrimmed(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.TrimSpace(v) == strings.TrimSpace(value)
+	})
+}
+
+// toQuestion converts a validated QuestionFile to the way questions are stored
+func (q *QuestionFile) toQuestion(courseUUID string) (Question, error) {
+	question := Question{
+		CourseUUID:    courseUUID,
+		QuestionType:  q.Type,
+		QuestionText:  q.Question,
+		Explanation:   q.Explanation,
+		CorrectAnswer: q.Answer,
+		Rubric:        q.Rubric,
+		LessonUUID:    q.LessonUUID,
+		LessonSlug:    q.LessonSlug,
+		ChapterUUID:   q.ChapterUUID,
+		ChapterSlug:   q.ChapterSlug,
+	}
+
+	marshal := func(values []string) (string, error) {
+		if len(values) == 0 {
+			return "", nil
+		}
+		b, err := json.Marshal(values)
+		return string(b), err
+	}
+
+	var err error
+	if question.AnswerChoices, err = marshal(q.Choices); err != nil {
+		return question, err
+	}
+	if question.AcceptedAnswers, err = marshal(q.AcceptedAnswers); err != nil {
+		return question, err
+	}
+	switch q.Type {
+	case "multi_select":
+		question.CorrectAnswer, err = marshal(q.Answers)
+	case "ordering":
+		// The choices are stored in the correct order, they are shuffled when the quiz runs
+		question.CorrectAnswer, err = marshal(q.Answers)
+		question.AnswerChoices = question.CorrectAnswer
+	}
+	return question, err
+}
+
+func questionToFile(q Question) QuestionFile {
+	f := QuestionFile{
+		Type:        q.QuestionType,
+		Question:    q.QuestionText,
+		Explanation: q.Explanation,
+		Rubric:      q.Rubric,
+		LessonUUID:  q.LessonUUID,
+		LessonSlug:  q.LessonSlug,
+		ChapterUUID: q.ChapterUUID,
+		ChapterSlug: q.ChapterSlug,
+	}
+	if q.AcceptedAnswers != "" {
+		json.Unmarshal([]byte(q.AcceptedAnswers), &f.AcceptedAnswers)
+	}
+	switch q.QuestionType {
+	case "multi_select":
+		f.Choices = q.GetAnswerChoices()
+		f.Answers = q.GetCorrectAnswers()
+	case "ordering":
+		f.Answers = q.GetCorrectAnswers()
+	default:
+		f.Choices = q.GetAnswerChoices()
+		f.Answer = q.CorrectAnswer
+	}
+	if len(f.Choices) == 0 {
+		f.Choices = nil
+	}
+	return f
+}
+
+// ContentHash identifies a question by its type, text and correct answer,
+// ignoring case and whitespace, so the same question is recognized across databases
+func (q *Question) ContentHash() string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+
+	answers := q.GetCorrectAnswers()
+	for i := range answers {
+		answers[i] = normalize(answers[i])
+	}
+	if q.QuestionType == "multi_select" {
+		slices.Sort(answers)
+	}
+
+	h := sha256.New()
+	for _, field := range append([]string{q.QuestionType, normalize(q.QuestionText)}, answers...) {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ExportQuiz builds the quiz file of a course deck, optionally with the answer history
+func ExportQuiz(db *sql.DB, courseUUID, deck string, withHistory bool) (*QuizFile, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	questions, err := getQuestions(db, QuestionFilter{CourseUUID: courseUUID, Deck: deck})
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("no questions found in deck %s of course %s", deck, courseUUID)
+	}
+
+	f := &QuizFile{
+		Version:    QuizFileVersion,
+		CourseUUID: courseUUID,
+		Deck:       deck,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		Questions:  make([]QuestionFile, 0, len(questions)),
+	}
+	for _, q := range questions {
+		qf := questionToFile(q)
+		if withHistory {
+			if qf.History, err = getAnswerHistory(db, q.ID); err != nil {
+				return nil, err
+			}
+		}
+		f.Questions = append(f.Questions, qf)
+	}
+	return f, nil
+}
+
+func getAnswerHistory(db *sql.DB, questionID int64) ([]AnswerFile, error) {
+	query := `
+		SELECT user_answer, is_correct, score, COALESCE(feedback, ''), COALESCE(scheduler, ''), answered_at
+		FROM user_answers
+		WHERE question_id = ?
+		ORDER BY answered_at ASC, id ASC
+	`
+	rows, err := db.Query(query, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting answer history for question %d: %w", questionID, err)
+	}
+	defer rows.Close()
+
+	var history []AnswerFile
+	for rows.Next() {
+		var a AnswerFile
+		var score sql.NullFloat64
+		if err := rows.Scan(&a.Answer, &a.Correct, &score, &a.Feedback, &a.Scheduler, &a.AnsweredAt); err != nil {
+			return nil, fmt.Errorf("error scanning answer history: %w", err)
+		}
+		if score.Valid {
+			a.Score = &score.Float64
+		}
+		history = append(history, a)
+	}
+	return history, rows.Err()
+}
+
+// ImportQuiz merges a quiz file into the database. Questions are matched by
+// ContentHash: known questions get their other fields updated, new ones are
+// added, so importing the same file twice changes nothing. Answers are only
+// imported with withHistory, skipping the ones already recorded.
+func ImportQuiz(db *sql.DB, f *QuizFile, withHistory bool) (ImportResult, error) {
+	var result ImportResult
+	if err := f.Validate(); err != nil {
+		return result, err
+	}
+
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	deck := f.GetDeck()
+	// Archived and flagged questions are matched too, so importing doesn't bring them back
+	existing, err := getQuestions(db, QuestionFilter{CourseUUID: f.CourseUUID, Deck: deck, IncludeArchived: true, IncludeFlagged: true})
+	if err != nil {
+		return result, err
+	}
+	byHash := map[string]Question{}
+	for _, q := range existing {
+		byHash[q.ContentHash()] = q
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	insertQuiz := `INSERT INTO quizzes (course_uuid, deck) VALUES (?, ?) ON CONFLICT (course_uuid, deck) DO NOTHING`
+	if _, err := tx.Exec(insertQuiz, f.CourseUUID, deck); err != nil {
+		return result, fmt.Errorf("failed to create quiz: %w", err)
+	}
+
+	for i, qf := range f.Questions {
+		q, err := qf.toQuestion(f.CourseUUID)
+		if err != nil {
+			return result, fmt.Errorf("question %d: %w", i+1, err)
+		}
+
+		hash := q.ContentHash()
+		if match, ok := byHash[hash]; ok {
+			q.ID = match.ID
+			if q.LessonUUID == "" && q.LessonSlug == "" {
+				// Formats like Markdown don't keep where a question comes from
+				q.LessonUUID, q.LessonSlug = match.LessonUUID, match.LessonSlug
+				q.ChapterUUID, q.ChapterSlug = match.ChapterUUID, match.ChapterSlug
+			}
+			if sameQuestion(q, match) {
+				result.Unchanged++
+			} else {
+				if err := updateQuestion(tx, q); err != nil {
+					return result, err
+				}
+				result.Updated++
+			}
+		} else {
+			if q.ID, err = insertQuestion(tx, deck, q); err != nil {
+				return result, err
+			}
+			result.Added++
+		}
+		byHash[hash] = q
+
+		if withHistory {
+			added, err := importHistory(tx, q.ID, qf.History)
+			if err != nil {
+				return result, err
+			}
+			result.AnswersAdded += added
+		}
+	}
+
+	return result, tx.Commit()
+}
+
+// sameQuestion compares the fields of two questions as they are exported, so
+// the way their JSON columns happen to be formatted does not matter
+func sameQuestion(a, b Question) bool {
+	return reflect.DeepEqual(questionToFile(a), questionToFile(b))
+}
+
+func insertQuestion(tx *sql.Tx, deck string, q Question) (int64, error) {
+	stmt := `
+	INSERT INTO questions (quiz_id, question_type_id, question_text, explanation, answer_choices, correct_answer, accepted_answers, rubric, lesson_uuid, lesson_slug, chapter_uuid, chapter_slug)
+	VALUES ((SELECT id FROM quizzes WHERE course_uuid = ? AND deck = ?), (SELECT id FROM question_types WHERE name = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := tx.Exec(stmt, q.CourseUUID, deck, q.QuestionType, q.QuestionText, q.Explanation, q.AnswerChoices, q.CorrectAnswer, q.AcceptedAnswers, q.Rubric, q.LessonUUID, q.LessonSlug, q.ChapterUUID, q.ChapterSlug)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add question %q: %w", q.QuestionText, err)
+	}
+	return result.LastInsertId()
+}
+
+func updateQuestion(tx *sql.Tx, q Question) error {
+	stmt := `
+	UPDATE questions
+	SET question_type_id = (SELECT id FROM question_types WHERE name = ?),
+		question_text = ?, explanation = ?, answer_choices = ?, correct_answer = ?, accepted_answers = ?, rubric = ?,
+		lesson_uuid = ?, lesson_slug = ?, chapter_uuid = ?, chapter_slug = ?
+	WHERE id = ?
+	`
+	_, err := tx.Exec(stmt, q.QuestionType, q.QuestionText, q.Explanation, q.AnswerChoices, q.CorrectAnswer, q.AcceptedAnswers, q.Rubric,
+		q.LessonUUID, q.LessonSlug, q.ChapterUUID, q.ChapterSlug, q.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update question %d: %w", q.ID, err)
+	}
+	return nil
+}
+
+// importHistory adds the answers not recorded yet. The schedules of a question
+// that got new answers are dropped, so they are rebuilt from the merged history.
+func importHistory(tx *sql.Tx, questionID int64, history []AnswerFile) (int, error) {
+	stmt := `
+	INSERT INTO user_answers (question_id, user_answer, is_correct, score, feedback, scheduler, answered_at)
+	SELECT ?, ?, ?, ?, ?, NULLIF(?, ''), ?
+	WHERE NOT EXISTS (
+		SELECT 1 FROM user_answers WHERE question_id = ? AND user_answer = ? AND answered_at = ?
+	)
+	`
+	added := 0
+	for _, a := range history {
+		answeredAt := a.AnsweredAt.UTC().Format(sqliteTimeFormat)
+		result, err := tx.Exec(stmt, questionID, a.Answer, a.Correct, a.Score, a.Feedback, a.Scheduler, answeredAt, questionID, a.Answer, answeredAt)
+		if err != nil {
+			return added, fmt.Errorf("failed to import answer history of question %d: %w", questionID, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return added, err
+		}
+		added += int(n)
+	}
+
+	if added > 0 {
+		if _, err := tx.Exec(`DELETE FROM question_schedules WHERE question_id = ?`, questionID); err != nil {
+			return added, fmt.Errorf("failed to reset schedules of question %d: %w", questionID, err)
+		}
+	}
+	return added, nil
+}
@@ -0,0 +1,149 @@
+package dao
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// quizDB returns a function opening the same migrated database, as ImportQuiz
+// and ExportQuiz close the one they are given
+func quizDB(t *testing.T) func() *sql.DB {
+	t.Helper()
+	config := DBConfig{DBPath: filepath.Join(t.TempDir(), "test.db")}
+	return func() *sql.DB {
+		db, err := InitializeDatabase(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+}
+
+func testQuizFile() *QuizFile {
+	return &QuizFile{
+		Version:    QuizFileVersion,
+		CourseUUID: "course",
+		Deck:       "joins",
+		Questions: []QuestionFile{
+			{Type: "multiple_choice", Question: "Which join keeps every left row?", Choices: []string{"INNER", "LEFT"}, Answer: "LEFT"},
+			{Type: "fill_blank", Question: "SELECT * _____ users", Answer: "FROM"},
+		},
+	}
+}
+
+func TestImportQuizTwice(t *testing.T) {
+	open := quizDB(t)
+
+	result, err := ImportQuiz(open(), testQuizFile(), false)
+	if err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	if result != (ImportResult{Added: 2}) {
+		t.Errorf("first import = %+v, want 2 added", result)
+	}
+
+	result, err = ImportQuiz(open(), testQuizFile(), false)
+	if err != nil {
+		t.Fatalf("second import: %v", err)
+	}
+	if result != (ImportResult{Unchanged: 2}) {
+		t.Errorf("second import = %+v, want 2 unchanged", result)
+	}
+}
+
+func TestImportQuizUpdatesEditedQuestions(t *testing.T) {
+	open := quizDB(t)
+	if _, err := ImportQuiz(open(), testQuizFile(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same text and answer, so the same question, with a new explanation and choice
+	edited := testQuizFile()
+	edited.Questions[0].Explanation = "LEFT keeps the rows without a match"
+	edited.Questions[0].Choices = append(edited.Questions[0].Choices, "CROSS")
+	result, err := ImportQuiz(open(), edited, false)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if result != (ImportResult{Updated: 1, Unchanged: 1}) {
+		t.Errorf("import = %+v, want 1 updated and 1 unchanged", result)
+	}
+
+	exported, err := ExportQuiz(open(), "course", "joins", false)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(exported.Questions) != 2 {
+		t.Fatalf("%d questions in the deck, want 2", len(exported.Questions))
+	}
+	for _, q := range exported.Questions {
+		if q.Type == "multiple_choice" && (q.Explanation != edited.Questions[0].Explanation || len(q.Choices) != 3) {
+			t.Errorf("question = %+v, want the edited one", q)
+		}
+	}
+}
+
+func TestImportQuizVersion1(t *testing.T) {
+	// Version 1 files have no deck nor lessons, their questions go to the default deck
+	v1 := `{"version": 1, "course_uuid": "course", "questions": [{"type": "fill_blank", "question": "SELECT * _____ users", "answer": "FROM"}]}`
+	f, err := DecodeQuizFile(strings.NewReader(v1), "json")
+	if err != nil {
+		t.Fatalf("DecodeQuizFile: %v", err)
+	}
+
+	open := quizDB(t)
+	if result, err := ImportQuiz(open(), f, false); err != nil || result.Added != 1 {
+		t.Fatalf("import = %+v, %v; want 1 added", result, err)
+	}
+	if exported, err := ExportQuiz(open(), "course", DefaultDeck, false); err != nil || len(exported.Questions) != 1 {
+		t.Errorf("default deck = %+v, %v; want the question", exported, err)
+	}
+
+	future := strings.Replace(v1, `"version": 1`, `"version": 3`, 1)
+	if _, err := DecodeQuizFile(strings.NewReader(future), "json"); err == nil || !strings.Contains(err.Error(), "unsupported quiz file version 3") {
+		t.Errorf("error = %v, want version 3 to be unsupported", err)
+	}
+}
+
+func TestQuizFileValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		question QuestionFile
+		want     string
+	}{
+		{
+			name:     "answer outside the choices",
+			question: QuestionFile{Type: "multiple_choice", Question: "Q", Choices: []string{"a", "b"}, Answer: "c"},
+			want:     `question 1: answer "c" is not one of the choices`,
+		},
+		{
+			name:     "one of the answers outside the choices",
+			question: QuestionFile{Type: "multi_select", Question: "Q", Choices: []string{"a", "b"}, Answers: []string{"a", "c"}},
+			want:     `question 1: answer "c" is not one of the choices`,
+		},
+		{
+			name:     "answer with extra spaces",
+			question: QuestionFile{Type: "multiple_choice", Question: "Q", Choices: []string{"a", "b"}, Answer: " b "},
+		},
+		{
+			name:     "unknown type",
+			question: QuestionFile{Type: "essay", Question: "Q", Answer: "a"},
+			want:     `question 1: unknown question type "essay"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &QuizFile{Version: QuizFileVersion, CourseUUID: "course", Questions: []QuestionFile{tt.question}}
+			err := f.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("error = %v, want none", err)
+				}
+			} else if err == nil || err.Error() != tt.want {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"math"
+	"time"
+)
+
+// fsrsWeights are the default FSRS-4.5 model parameters
+var fsrsWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+const (
+	fsrsDecay            = -0.5
+	fsrsFactor           = 19.0 / 81.0
+	fsrsDesiredRetention = 0.9
+	fsrsMaxIntervalDays  = 36500
+	fsrsRatingAgain      = 1
+	fsrsRatingHard       = 2
+	fsrsRatingGood       = 3
+	fsrsRatingEasy       = 4
+	fsrsMinDifficulty    = 1.0
+	fsrsMaxDifficulty    = 10.0
+	fsrsSecondsPerDay    = 24 * 60 * 60
+	fsrsMinStability     = 0.01
+)
+
+// fsrsScheduler implements the Free Spaced Repetition Scheduler (FSRS-4.5),
+// which models each question's memory with a stability and a difficulty
+type fsrsScheduler struct{}
+
+func (fsrsScheduler) Name() string { return "fsrs" }
+
+func (fsrsScheduler) NewState(questionID int64, now time.Time) ReviewState {
+	return ReviewState{
+		QuestionID: questionID,
+		DueAt:      now,
+	}
+}
+
+func (fsrsScheduler) Review(s ReviewState, quality int, now time.Time) ReviewState {
+	rating := fsrsRating(quality)
+	w := fsrsWeights
+
+	if s.IsNew() {
+		s.Stability = w[rating-1]
+		s.Difficulty = fsrsInitialDifficulty(rating)
+	} else {
+		elapsedDays := max(0, now.Sub(s.LastReviewedAt).Seconds()/fsrsSecondsPerDay)
+		r := fsrsRetrievability(elapsedDays, s.Stability)
+
+		nextDifficulty := s.Difficulty - w[6]*float64(rating-3)
+		s.Difficulty = clampDifficulty(w[7]*fsrsInitialDifficulty(fsrsRatingGood) + (1-w[7])*nextDifficulty)
+
+		if rating == fsrsRatingAgain {
+			forget := w[11] * math.Pow(s.Difficulty, -w[12]) * (math.Pow(s.Stability+1, w[13]) - 1) * math.Exp(w[14]*(1-r))
+			s.Stability = min(forget, s.Stability)
+		} else {
+			hardPenalty, easyBonus := 1.0, 1.0
+			if rating == fsrsRatingHard {
+				hardPenalty = w[15]
+			}
+			if rating == fsrsRatingEasy {
+				easyBonus = w[16]
+			}
+			growth := math.Exp(w[8]) * (11 - s.Difficulty) * math.Pow(s.Stability, -w[9]) * (math.Exp(w[10]*(1-r)) - 1)
+			s.Stability = s.Stability * (1 + growth*hardPenalty*easyBonus)
+		}
+	}
+	s.Stability = max(s.Stability, fsrsMinStability)
+
+	if rating == fsrsRatingAgain {
+		s.Repetitions = 0
+		s.Lapses++
+		s.IntervalDays = 1
+	} else {
+		s.Repetitions++
+		s.IntervalDays = fsrsInterval(s.Stability)
+	}
+
+	s.LastReviewedAt = now
+	s.DueAt = now.AddDate(0, 0, s.IntervalDays)
+	return s
+}
+
+// fsrsRating maps a quality grade (0-5) to an FSRS rating (Again, Hard, Good, Easy)
+func fsrsRating(quality int) int {
+	switch {
+	case quality < 3:
+		return fsrsRatingAgain
+	case quality == 3:
+		return fsrsRatingHard
+	case quality == 4:
+		return fsrsRatingGood
+	default:
+		return fsrsRatingEasy
+	}
+}
+
+func fsrsInitialDifficulty(rating int) float64 {
+	return clampDifficulty(fsrsWeights[4] - float64(rating-3)*fsrsWeights[5])
+}
+
+func clampDifficulty(d float64) float64 {
+	return max(fsrsMinDifficulty, min(fsrsMaxDifficulty, d))
+}
+
+// fsrsRetrievability is the probability of recalling a question after elapsedDays
+func fsrsRetrievability(elapsedDays, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsedDays/stability, fsrsDecay)
+}
+
+// fsrsInterval returns the number of days until recall probability drops to the desired retention
+func fsrsInterval(stability float64) int {
+	interval := stability / fsrsFactor * (math.Pow(fsrsDesiredRetention, 1/fsrsDecay) - 1)
+	return max(1, min(fsrsMaxIntervalDays, int(math.Round(interval))))
+}
@@ -0,0 +1,84 @@
+package dao
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFSRSFirstReview(t *testing.T) {
+	// With the default FSRS-4.5 weights, S0 = w[rating-1], D0 = w4 - (rating-3) * w5,
+	// and at 90% retention the interval is the stability
+	tests := []struct {
+		quality    int
+		stability  float64
+		difficulty float64
+		interval   int
+	}{
+		{0, 0.4872, 7.6214, 1},
+		{2, 0.4872, 7.6214, 1},
+		{3, 1.4003, 6.3916, 1},
+		{4, 3.7145, 5.1618, 4},
+		{5, 13.8206, 3.9320, 14},
+	}
+	for _, tt := range tests {
+		s := fsrsScheduler{}.Review(fsrsScheduler{}.NewState(1, reviewStart), tt.quality, reviewStart)
+		if math.Abs(s.Stability-tt.stability) > 1e-4 || math.Abs(s.Difficulty-tt.difficulty) > 1e-4 || s.IntervalDays != tt.interval {
+			t.Errorf("quality %d: stability %.4f, difficulty %.4f, interval %d; want %.4f, %.4f, %d",
+				tt.quality, s.Stability, s.Difficulty, s.IntervalDays, tt.stability, tt.difficulty, tt.interval)
+		}
+	}
+}
+
+func TestFSRSSecondReview(t *testing.T) {
+	// After a first Good answer (S 3.7145, D 5.1618), reviewed 4 days later
+	// when the retrievability is 0.8935
+	tests := []struct {
+		name       string
+		quality    int
+		stability  float64
+		difficulty float64
+		interval   int
+		lapses     int
+	}{
+		{"again", 1, 1.4006, 6.9012, 1, 1},
+		{"hard", 3, 5.8595, 6.0315, 6, 0},
+		{"good", 4, 14.8081, 5.1618, 15, 0},
+		{"easy", 5, 40.3660, 4.2921, 40, 0},
+	}
+	for _, tt := range tests {
+		states := reviewOnDue(fsrsScheduler{}, 4, tt.quality)
+		if states[0].IntervalDays != 4 {
+			t.Fatalf("first interval %d, want 4", states[0].IntervalDays)
+		}
+		s := states[1]
+		if math.Abs(s.Stability-tt.stability) > 1e-4 || math.Abs(s.Difficulty-tt.difficulty) > 1e-4 {
+			t.Errorf("%s: stability %.4f, difficulty %.4f; want %.4f, %.4f", tt.name, s.Stability, s.Difficulty, tt.stability, tt.difficulty)
+		}
+		if s.IntervalDays != tt.interval || s.Lapses != tt.lapses {
+			t.Errorf("%s: interval %d, lapses %d; want %d, %d", tt.name, s.IntervalDays, s.Lapses, tt.interval, tt.lapses)
+		}
+	}
+}
+
+func TestFSRSRetrievability(t *testing.T) {
+	if r := fsrsRetrievability(0, 3.7145); r != 1 {
+		t.Errorf("retrievability right after a review = %v, want 1", r)
+	}
+	// The interval is when retrievability drops to the desired retention
+	if r := fsrsRetrievability(10, 10); math.Abs(r-fsrsDesiredRetention) > 1e-9 {
+		t.Errorf("retrievability after the stability = %v, want %v", r, fsrsDesiredRetention)
+	}
+	if i := fsrsInterval(1e6); i != fsrsMaxIntervalDays {
+		t.Errorf("interval of a huge stability = %d, want the maximum %d", i, fsrsMaxIntervalDays)
+	}
+}
+
+func TestFSRSDifficultyStaysInRange(t *testing.T) {
+	for _, quality := range []int{0, 5} {
+		for i, s := range reviewOnDue(fsrsScheduler{}, quality, quality, quality, quality, quality, quality) {
+			if s.Difficulty < fsrsMinDifficulty || s.Difficulty > fsrsMaxDifficulty || s.Stability < fsrsMinStability {
+				t.Errorf("quality %d, review %d: difficulty %v, stability %v out of range", quality, i+1, s.Difficulty, s.Stability)
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package dao
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// AnswerMatching configures how typed answers are compared to the expected ones
+type AnswerMatching struct {
+	CaseSensitive    bool
+	StripWhitespace  bool
+	StripPunctuation bool
+	// MaxTypos is the largest Levenshtein distance still accepted as correct.
+	// Short answers get less slack: at most one typo per five characters, so
+	// answers under five characters must be exact.
+	MaxTypos int
+}
+
+// AnswerMatchingFromConfig reads the quiz.fill_blank.* config keys
+func AnswerMatchingFromConfig() AnswerMatching {
+	return AnswerMatching{
+		CaseSensitive:    viper.GetBool("quiz.fill_blank.case_sensitive"),
+		StripWhitespace:  viper.GetBool("quiz.fill_blank.strip_whitespace"),
+		StripPunctuation: viper.GetBool("quiz.fill_blank.strip_punctuation"),
+		MaxTypos:         viper.GetInt("quiz.fill_blank.max_typos"),
+	}
+}
+
+// PassScore is the lowest score of a graded answer that still counts as correct
+const PassScore = 0.6
+
+// Grade is the result of checking an answer
+type Grade struct {
+	Correct bool
+	// Score ranges from 0 to 1
+	Score float64
+	// Quality is the SM-2 style grade (0-5) passed to the scheduler
+	Quality int
+	// Matched is the accepted answer closest to what was typed
+	Matched string
+	// Feedback explains the score of a free_response answer
+	Feedback string
+}
+
+// ScoreGrade turns a score between 0 and 1 into a grade. The quality agrees
+// with Correct: schedulers count a quality of 3 or more as recalled, so a score
+// below PassScore is capped at 2.
+func ScoreGrade(score float64, feedback string) Grade {
+	score = max(0, min(1, score))
+	correct := score >= PassScore
+	quality := int(math.Round(score * 5))
+	if correct {
+		quality = max(quality, 3)
+	} else {
+		quality = min(quality, 2)
+	}
+	return Grade{
+		Correct:  correct,
+		Score:    score,
+		Quality:  quality,
+		Feedback: feedback,
+	}
+}
+
+func binaryGrade(isCorrect bool, matched string) Grade {
+	g := Grade{Correct: isCorrect, Quality: AnswerQuality(isCorrect), Matched: matched}
+	if isCorrect {
+		g.Score = 1
+	}
+	return g
+}
+
+func (m AnswerMatching) normalize(s string) string {
+	s = strings.TrimSpace(s)
+	if !m.CaseSensitive {
+		s = strings.ToLower(s)
+	}
+	return strings.Map(func(r rune) rune {
+		if m.StripWhitespace && unicode.IsSpace(r) {
+			return -1
+		}
+		if m.StripPunctuation && (unicode.IsPunct(r) || unicode.IsSymbol(r)) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Match compares an answer to a list of accepted answers. An exact match
+// (after normalization) gets a higher quality than one within the typo tolerance.
+func (m AnswerMatching) Match(answer string, accepted []string) Grade {
+	typed := m.normalize(answer)
+	if typed == "" {
+		return binaryGrade(false, "")
+	}
+
+	for _, a := range accepted {
+		if typed == m.normalize(a) {
+			return binaryGrade(true, a)
+		}
+	}
+
+	for _, a := range accepted {
+		expected := m.normalize(a)
+		allowed := min(m.MaxTypos, len([]rune(expected))/5)
+		if allowed > 0 && levenshtein(typed, expected) <= allowed {
+			return Grade{Correct: true, Score: 1, Quality: 3, Matched: a}
+		}
+	}
+
+	return binaryGrade(false, "")
+}
+
+// GetAcceptedAnswers returns the correct answer followed by its accepted alternatives
+func (q *Question) GetAcceptedAnswers() []string {
+	accepted := []string{q.CorrectAnswer}
+	alternatives := []string{}
+	if q.AcceptedAnswers != "" {
+		json.Unmarshal([]byte(q.AcceptedAnswers), &alternatives)
+	}
+	return append(accepted, alternatives...)
+}
+
+// GradeAnswer checks an answer according to the question type.
+// multi_select answers are a JSON array of the selected choices, ordering
+// answers a JSON array of the items in the order given.
+// free_response answers need an LLM and are graded by the api package instead.
+func (q *Question) GradeAnswer(answer string) Grade {
+	switch q.QuestionType {
+	case "fill_blank":
+		return AnswerMatchingFromConfig().Match(answer, q.GetAcceptedAnswers())
+	case "multi_select":
+		selected := []string{}
+		json.Unmarshal([]byte(answer), &selected)
+		return q.gradeSelection(selected, viper.GetBool("quiz.multi_select.partial_credit"))
+	case "ordering":
+		order := []string{}
+		json.Unmarshal([]byte(answer), &order)
+		return q.gradeOrdering(order)
+	default:
+		return binaryGrade(q.IsCorrectAnswer(answer), q.CorrectAnswer)
+	}
+}
+
+// gradeSelection grades the choices picked for a multi_select question. With
+// partial credit every wrong pick cancels a right one; without it only the
+// exact set of correct choices scores.
+func (q *Question) gradeSelection(selected []string, partialCredit bool) Grade {
+	correct := q.GetCorrectAnswers()
+	hits, misses := 0, 0
+	for _, choice := range selected {
+		if q.IsCorrectChoice(choice) {
+			hits++
+		} else {
+			misses++
+		}
+	}
+
+	exact := hits == len(correct) && misses == 0
+	if !partialCredit || len(correct) == 0 {
+		return binaryGrade(exact, q.CorrectAnswer)
+	}
+
+	grade := ScoreGrade(float64(hits-misses)/float64(len(correct)), "")
+	grade.Matched = q.CorrectAnswer
+	return grade
+}
+
+// gradeOrdering scores an ordering by the share of item pairs placed in the
+// right relative order, so swapping two neighbours costs less than reversing everything
+func (q *Question) gradeOrdering(order []string) Grade {
+	correct := q.GetCorrectAnswers()
+	if slices.Equal(order, correct) {
+		return binaryGrade(true, q.CorrectAnswer)
+	}
+
+	position := map[string]int{}
+	for i, item := range correct {
+		position[item] = i
+	}
+
+	pairs, concordant := 0, 0
+	for i := 0; i < len(order); i++ {
+		for j := i + 1; j < len(order); j++ {
+			pi, okI := position[order[i]]
+			pj, okJ := position[order[j]]
+			pairs++
+			if okI && okJ && pi < pj {
+				concordant++
+			}
+		}
+	}
+	if pairs == 0 || len(order) != len(correct) {
+		return binaryGrade(false, q.CorrectAnswer)
+	}
+
+	grade := ScoreGrade(float64(concordant)/float64(pairs), "")
+	grade.Matched = q.CorrectAnswer
+	return grade
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
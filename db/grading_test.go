@@ -0,0 +1,140 @@
+package dao
+
+import "testing"
+
+func TestScoreGrade(t *testing.T) {
+	tests := []struct {
+		score   float64
+		correct bool
+		quality int
+	}{
+		{-0.5, false, 0},
+		{0, false, 0},
+		{0.3, false, 2},
+		{0.5, false, 2},
+		{0.59, false, 2},
+		{PassScore, true, 3},
+		{0.7, true, 4},
+		{1, true, 5},
+		{1.5, true, 5},
+	}
+	for _, tt := range tests {
+		g := ScoreGrade(tt.score, "")
+		if g.Correct != tt.correct || g.Quality != tt.quality {
+			t.Errorf("ScoreGrade(%v) = correct %v, quality %d; want %v, %d", tt.score, g.Correct, g.Quality, tt.correct, tt.quality)
+		}
+		if g.Score < 0 || g.Score > 1 {
+			t.Errorf("ScoreGrade(%v).Score = %v, want it clamped to [0, 1]", tt.score, g.Score)
+		}
+	}
+}
+
+func TestScoreGradeQualityAgreesWithSchedulers(t *testing.T) {
+	for i := 0; i <= 100; i++ {
+		g := ScoreGrade(float64(i)/100, "")
+		if recalled := g.Quality >= 3; recalled != g.Correct {
+			t.Errorf("score %.2f: correct %v but quality %d", float64(i)/100, g.Correct, g.Quality)
+		}
+	}
+}
+
+func TestPartialCreditBelowPassScoreIsNotRecalled(t *testing.T) {
+	// Two of four correct choices: a score of 0.5
+	selection := &Question{QuestionType: "multi_select", CorrectAnswer: `["a","b","c","d"]`}
+	if g := selection.gradeSelection([]string{"a", "b"}, true); g.Correct || g.Quality >= 3 {
+		t.Errorf("multi_select half right = correct %v, quality %d; want not recalled", g.Correct, g.Quality)
+	}
+
+	// One of the three pairs out of order: a score of 0.67
+	ordering := &Question{QuestionType: "ordering", CorrectAnswer: `["a","b","c"]`}
+	if g := ordering.gradeOrdering([]string{"b", "a", "c"}); !g.Correct || g.Quality < 3 {
+		t.Errorf("ordering with one swap = correct %v, quality %d; want recalled", g.Correct, g.Quality)
+	}
+	// Two of the three pairs out of order: a score of 0.33
+	if g := ordering.gradeOrdering([]string{"b", "c", "a"}); g.Correct || g.Quality >= 3 {
+		t.Errorf("ordering with two swaps = correct %v, quality %d; want not recalled", g.Correct, g.Quality)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	lenient := AnswerMatching{StripWhitespace: true, StripPunctuation: true}
+	tests := []struct {
+		matching AnswerMatching
+		input    string
+		want     string
+	}{
+		{lenient, "  Hello, World!  ", "helloworld"},
+		{lenient, "O(log n)", "ologn"},
+		{lenient, "a\tb\nc", "abc"},
+		{lenient, "Ünïcode", "ünïcode"},
+		{AnswerMatching{}, "  Hello, World!  ", "hello, world!"},
+		{AnswerMatching{CaseSensitive: true, StripWhitespace: true}, "Hello, World!", "Hello,World!"},
+		{AnswerMatching{StripPunctuation: true}, "x := a + b", "x  a  b"},
+	}
+	for _, tt := range tests {
+		if got := tt.matching.normalize(tt.input); got != tt.want {
+			t.Errorf("%+v.normalize(%q) = %q, want %q", tt.matching, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"goroutine", "gorotuine", 2},
+		{"café", "cafe", 1},
+		{"same", "same", 0},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	lenient := AnswerMatching{StripWhitespace: true, StripPunctuation: true, MaxTypos: 1}
+	tests := []struct {
+		name     string
+		matching AnswerMatching
+		answer   string
+		accepted []string
+		correct  bool
+		quality  int
+		matched  string
+	}{
+		{"exact", lenient, "goroutine", []string{"goroutine"}, true, 4, "goroutine"},
+		{"case, spacing and punctuation", lenient, " Go-Routine! ", []string{"goroutine"}, true, 4, "goroutine"},
+		{"alternative", lenient, "chan", []string{"channel", "chan"}, true, 4, "chan"},
+		{"one typo", lenient, "gorutine", []string{"goroutine"}, true, 3, "goroutine"},
+		{"two typos", lenient, "grutine", []string{"goroutine"}, false, 1, ""},
+		{"two typos allowed", AnswerMatching{MaxTypos: 2}, "concurancy", []string{"concurrency"}, true, 3, "concurrency"},
+		// Under five characters, no typo is forgiven
+		{"short answer typo", lenient, "car", []string{"cat"}, false, 1, ""},
+		{"five characters typo", lenient, "slics", []string{"slice"}, true, 3, "slice"},
+		// The cap also applies to MaxTypos above one: one for nine characters
+		{"cap on long answers", AnswerMatching{MaxTypos: 2}, "grutine", []string{"goroutine"}, false, 1, ""},
+		{"no typos", AnswerMatching{StripWhitespace: true}, "gorutine", []string{"goroutine"}, false, 1, ""},
+		{"case sensitive", AnswerMatching{CaseSensitive: true}, "Goroutine", []string{"goroutine"}, false, 1, ""},
+		{"case sensitive typo", AnswerMatching{CaseSensitive: true, MaxTypos: 1}, "Goroutine", []string{"goroutine"}, true, 3, "goroutine"},
+		{"case sensitive short", AnswerMatching{CaseSensitive: true, MaxTypos: 1}, "Go", []string{"go"}, false, 1, ""},
+		{"empty", lenient, "  ", []string{"goroutine"}, false, 1, ""},
+	}
+	for _, tt := range tests {
+		g := tt.matching.Match(tt.answer, tt.accepted)
+		if g.Correct != tt.correct || g.Quality != tt.quality || g.Matched != tt.matched {
+			t.Errorf("%s: Match(%q) = correct %v, quality %d, matched %q; want %v, %d, %q",
+				tt.name, tt.answer, g.Correct, g.Quality, g.Matched, tt.correct, tt.quality, tt.matched)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package dao
+
+import "time"
+
+// leitnerIntervals holds the review interval in days for each box
+var leitnerIntervals = []int{1, 2, 4, 8, 16}
+
+// leitnerScheduler implements a Leitner box system: a correct answer moves
+// the question up one box, a wrong one sends it back to the first box
+type leitnerScheduler struct{}
+
+func (leitnerScheduler) Name() string { return "leitner" }
+
+func (leitnerScheduler) NewState(questionID int64, now time.Time) ReviewState {
+	return ReviewState{
+		QuestionID: questionID,
+		DueAt:      now,
+	}
+}
+
+func (leitnerScheduler) Review(s ReviewState, quality int, now time.Time) ReviewState {
+	if quality >= 3 {
+		s.Box = min(s.Box+1, len(leitnerIntervals))
+		s.Repetitions++
+	} else {
+		s.Box = 1
+		s.Repetitions = 0
+		s.Lapses++
+	}
+
+	s.IntervalDays = leitnerIntervals[s.Box-1]
+	s.LastReviewedAt = now
+	s.DueAt = now.AddDate(0, 0, s.IntervalDays)
+	return s
+}
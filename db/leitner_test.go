@@ -0,0 +1,33 @@
+package dao
+
+import "testing"
+
+func TestLeitnerBoxes(t *testing.T) {
+	tests := []struct {
+		name      string
+		qualities []int
+		boxes     []int
+		intervals []int
+	}{
+		{"up to the last box", []int{3, 4, 5, 3, 3, 3}, []int{1, 2, 3, 4, 5, 5}, []int{1, 2, 4, 8, 16, 16}},
+		{"back to the first box", []int{4, 4, 4, 2, 4}, []int{1, 2, 3, 1, 2}, []int{1, 2, 4, 1, 2}},
+		{"wrong when new", []int{0, 0}, []int{1, 1}, []int{1, 1}},
+	}
+	for _, tt := range tests {
+		for i, s := range reviewOnDue(leitnerScheduler{}, tt.qualities...) {
+			if s.Box != tt.boxes[i] || s.IntervalDays != tt.intervals[i] {
+				t.Errorf("%s, review %d: box %d, interval %d; want %d, %d", tt.name, i+1, s.Box, s.IntervalDays, tt.boxes[i], tt.intervals[i])
+			}
+		}
+	}
+}
+
+func TestLeitnerLapse(t *testing.T) {
+	states := reviewOnDue(leitnerScheduler{}, 4, 4, 1)
+	if s := states[2]; s.Repetitions != 0 || s.Lapses != 1 {
+		t.Errorf("after a wrong answer: %d repetitions, %d lapses; want 0, 1", s.Repetitions, s.Lapses)
+	}
+	if s := states[1]; s.Repetitions != 2 || s.Lapses != 0 {
+		t.Errorf("after 2 right answers: %d repetitions, %d lapses; want 2, 0", s.Repetitions, s.Lapses)
+	}
+}
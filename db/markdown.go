@@ -0,0 +1,271 @@
+package dao
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarkdownQuestionTypes are the question types the Markdown format can express
+var MarkdownQuestionTypes = []string{"multiple_choice", "fill_blank", "multi_select", "ordering"}
+
+var (
+	mdCheckbox = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	mdBullet   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdNumbered = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+)
+
+// writeMarkdown writes a quiz file in the Markdown format, see docs/quiz-format.md.
+// Questions of types the format can't express are left out.
+func writeMarkdown(w io.Writer, f *QuizFile) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "---\ncourse_uuid: %s\n", f.CourseUUID)
+	if f.Deck != "" && f.Deck != DefaultDeck {
+		fmt.Fprintf(bw, "deck: %s\n", f.Deck)
+	}
+	bw.WriteString("---\n")
+
+	for _, q := range f.Questions {
+		if !slices.Contains(MarkdownQuestionTypes, q.Type) {
+			continue
+		}
+
+		title, body, _ := strings.Cut(q.Question, "\n")
+		fmt.Fprintf(bw, "\n## %s\n\n", strings.TrimSpace(title))
+		if body = strings.TrimSpace(body); body != "" {
+			fmt.Fprintf(bw, "%s\n\n", body)
+		}
+
+		switch q.Type {
+		case "multiple_choice":
+			for _, choice := range q.Choices {
+				writeMarkdownCheckbox(bw, choice, strings.TrimSpace(choice) == strings.TrimSpace(q.Answer))
+			}
+		case "multi_select":
+			for _, choice := range q.Choices {
+				writeMarkdownCheckbox(bw, choice, containsTrimmed(q.Answers, choice))
+			}
+		case "fill_blank":
+			writeMarkdownCheckbox(bw, q.Answer, true)
+			for _, accepted := range q.AcceptedAnswers {
+				writeMarkdownCheckbox(bw, accepted, true)
+			}
+		case "ordering":
+			for i, item := range q.Answers {
+				fmt.Fprintf(bw, "%d. %s\n", i+1, item)
+			}
+		}
+
+		if q.Explanation != "" {
+			bw.WriteString("\n")
+			for _, line := range strings.Split(strings.TrimSpace(q.Explanation), "\n") {
+				fmt.Fprintf(bw, "> %s\n", line)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func writeMarkdownCheckbox(w io.Writer, text string, checked bool) {
+	mark := " "
+	if checked {
+		mark = "x"
+	}
+	fmt.Fprintf(w, "- [%s] %s\n", mark, text)
+}
+
+// markdownQuestion collects the lines of a question while it is parsed
+type markdownQuestion struct {
+	line        int
+	title       string
+	body        []string
+	choices     []string
+	checked     []string
+	unchecked   []string
+	ordered     []string
+	explanation []string
+}
+
+func (m *markdownQuestion) hasAnswers() bool {
+	return len(m.checked) > 0 || len(m.unchecked) > 0 || len(m.ordered) > 0
+}
+
+// toQuestionFile picks the question type from the shape of the answers:
+// a numbered list is an ordering question, checkboxes that are all checked
+// the answer and accepted alternatives of a fill_blank question, otherwise
+// a multiple_choice or multi_select question depending on how many are checked
+func (m *markdownQuestion) toQuestionFile() (QuestionFile, error) {
+	q := QuestionFile{
+		Question:    strings.Join(append([]string{m.title}, trimBlankLines(m.body)...), "\n"),
+		Explanation: strings.Join(trimBlankLines(m.explanation), "\n"),
+	}
+
+	switch {
+	case len(m.ordered) > 0 && (len(m.checked) > 0 || len(m.unchecked) > 0):
+		return q, fmt.Errorf("mixes a numbered list with checkboxes")
+	case len(m.ordered) > 0:
+		q.Type = "ordering"
+		q.Answers = m.ordered
+	case len(m.checked) == 0:
+		return q, fmt.Errorf("has no answer, mark the correct one with [x]")
+	case len(m.unchecked) == 0:
+		q.Type = "fill_blank"
+		q.Answer = m.checked[0]
+		q.AcceptedAnswers = m.checked[1:]
+		if len(q.AcceptedAnswers) == 0 {
+			q.AcceptedAnswers = nil
+		}
+	case len(m.checked) == 1:
+		q.Type = "multiple_choice"
+		q.Answer = m.checked[0]
+		q.Choices = m.choices
+	default:
+		q.Type = "multi_select"
+		q.Answers = m.checked
+		q.Choices = m.choices
+	}
+	return q, q.Validate()
+}
+
+func trimBlankLines(lines []string) []string {
+	start, end := 0, len(lines)
+	for start < end && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return lines[start:end]
+}
+
+// readMarkdown parses the Markdown format, reporting every invalid question
+// with its line number
+func readMarkdown(r io.Reader) (*QuizFile, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	f := &QuizFile{Version: QuizFileVersion}
+	var errs []error
+	var current *markdownQuestion
+	lineNumber := 0
+	inFence := false
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		q, err := current.toQuestionFile()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: question %q %w", current.line, current.title, err))
+		} else {
+			f.Questions = append(f.Questions, q)
+		}
+		current = nil
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		lineNumber++
+
+		if lineNumber == 1 && line == "---" {
+			frontMatter, err := readFrontMatter(scanner, &lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			f.CourseUUID = frontMatter.CourseUUID
+			f.Deck = frontMatter.Deck
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if inFence || strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if current == nil {
+				continue
+			}
+			if current.hasAnswers() {
+				errs = append(errs, fmt.Errorf("line %d: code blocks must come before the answers", lineNumber))
+				continue
+			}
+			current.body = append(current.body, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "## "):
+			finish()
+			current = &markdownQuestion{line: lineNumber, title: strings.TrimSpace(line[3:])}
+		case strings.HasPrefix(line, "# "):
+			// The title of the document
+			finish()
+		case current == nil:
+			// Text before the first question
+		case strings.HasPrefix(line, ">"):
+			current.explanation = append(current.explanation, strings.TrimPrefix(strings.TrimPrefix(line, ">"), " "))
+		case mdCheckbox.MatchString(line):
+			match := mdCheckbox.FindStringSubmatch(line)
+			text := strings.TrimSpace(match[2])
+			if match[1] == " " {
+				current.unchecked = append(current.unchecked, text)
+			} else {
+				current.checked = append(current.checked, text)
+			}
+			current.choices = append(current.choices, text)
+		case mdNumbered.MatchString(line):
+			current.ordered = append(current.ordered, strings.TrimSpace(mdNumbered.FindStringSubmatch(line)[1]))
+		case mdBullet.MatchString(line):
+			// A plain bullet is an unchecked choice, only [x] marks the correct ones
+			text := strings.TrimSpace(mdBullet.FindStringSubmatch(line)[1])
+			current.unchecked = append(current.unchecked, text)
+			current.choices = append(current.choices, text)
+		case strings.TrimSpace(line) == "":
+			if len(current.explanation) > 0 {
+				current.explanation = append(current.explanation, "")
+			} else if !current.hasAnswers() {
+				current.body = append(current.body, "")
+			}
+		case current.hasAnswers():
+			errs = append(errs, fmt.Errorf("line %d: text after the answers must be a > quote", lineNumber))
+		default:
+			current.body = append(current.body, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read markdown: %w", err)
+	}
+	finish()
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return f, nil
+}
+
+type markdownFrontMatter struct {
+	CourseUUID string `yaml:"course_uuid"`
+	Deck       string `yaml:"deck"`
+}
+
+// readFrontMatter reads the YAML block between the --- lines opening the document
+func readFrontMatter(scanner *bufio.Scanner, lineNumber *int) (markdownFrontMatter, error) {
+	var frontMatter markdownFrontMatter
+	var lines []string
+	for scanner.Scan() {
+		*lineNumber++
+		if strings.TrimSpace(scanner.Text()) == "---" {
+			if err := yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &frontMatter); err != nil {
+				return frontMatter, fmt.Errorf("invalid front matter: %w", err)
+			}
+			return frontMatter, nil
+		}
+		lines = append(lines, scanner.Text())
+	}
+	return frontMatter, fmt.Errorf("front matter is not closed with ---")
+}
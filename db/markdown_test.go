@@ -0,0 +1,194 @@
+package dao
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	f := &QuizFile{
+		Version:    QuizFileVersion,
+		CourseUUID: "3b39d0f6-f944-4f1b-832d-a1daba32eda4",
+		Deck:       "joins",
+		Questions: []QuestionFile{
+			{
+				Type:        "multiple_choice",
+				Question:    "What does this query return?\n```sql\nSELECT COUNT(*) FROM users WHERE 1 = 0;\n```",
+				Choices:     []string{"0", "NULL", "nothing"},
+				Answer:      "0",
+				Explanation: "COUNT never returns NULL.\nIt counts no rows here.",
+			},
+			{
+				Type:     "multi_select",
+				Question: "Which are aggregate functions?",
+				Choices:  []string{"COUNT", "LOWER", "SUM"},
+				Answers:  []string{"COUNT", "SUM"},
+			},
+			{
+				Type:            "fill_blank",
+				Question:        "A _____ key uniquely identifies each row.",
+				Answer:          "primary",
+				AcceptedAnswers: []string{"PK"},
+			},
+			{
+				Type:     "fill_blank",
+				Question: "The clause that sorts rows is ORDER _____.",
+				Answer:   "BY",
+			},
+			{
+				Type:     "ordering",
+				Question: "Put the TCP handshake in order",
+				Answers:  []string{"SYN", "SYN-ACK", "ACK"},
+			},
+			{
+				Type:     "free_response",
+				Question: "Explain what an index is for.",
+				Answer:   "Finding rows without scanning the table.",
+				Rubric:   "Mentions lookups",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeMarkdown(&buf, f); err != nil {
+		t.Fatalf("writeMarkdown: %v", err)
+	}
+	if strings.Contains(buf.String(), "index") {
+		t.Errorf("free_response question was written:\n%s", buf.String())
+	}
+
+	got, err := readMarkdown(&buf)
+	if err != nil {
+		t.Fatalf("readMarkdown: %v\n%s", err, buf.String())
+	}
+	if got.CourseUUID != f.CourseUUID || got.Deck != f.Deck {
+		t.Errorf("course %q deck %q, want %q and %q", got.CourseUUID, got.Deck, f.CourseUUID, f.Deck)
+	}
+	// Every question but the free_response one comes back unchanged
+	want := f.Questions[:len(f.Questions)-1]
+	if !reflect.DeepEqual(got.Questions, want) {
+		t.Errorf("questions =\n%+v\nwant\n%+v", got.Questions, want)
+	}
+}
+
+func TestReadMarkdownAnswers(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     QuestionFile
+	}{
+		{
+			name:     "plain bullets are wrong choices",
+			markdown: "## Which clause filters rows?\n\n- FILTER\n- [x] WHERE\n* HAVING\n",
+			want: QuestionFile{
+				Type:     "multiple_choice",
+				Question: "Which clause filters rows?",
+				Choices:  []string{"FILTER", "WHERE", "HAVING"},
+				Answer:   "WHERE",
+			},
+		},
+		{
+			name:     "plain bullets and unchecked boxes mixed",
+			markdown: "## Which are joins?\n\n- [x] INNER\n- [ ] UNION\n- [X] LEFT\n+ EXCEPT\n",
+			want: QuestionFile{
+				Type:     "multi_select",
+				Question: "Which are joins?",
+				Choices:  []string{"INNER", "UNION", "LEFT", "EXCEPT"},
+				Answers:  []string{"INNER", "LEFT"},
+			},
+		},
+		{
+			name:     "every box checked",
+			markdown: "## Capital of France?\n\n- [x] Paris\n- [x] paris, France\n",
+			want: QuestionFile{
+				Type:            "fill_blank",
+				Question:        "Capital of France?",
+				Answer:          "Paris",
+				AcceptedAnswers: []string{"paris, France"},
+			},
+		},
+		{
+			name:     "numbered list",
+			markdown: "# Title\n\nIntro text\n\n## Sort them\n\n1. one\n2) two\n3. three\n\n> Counting\n",
+			want: QuestionFile{
+				Type:        "ordering",
+				Question:    "Sort them",
+				Answers:     []string{"one", "two", "three"},
+				Explanation: "Counting",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := readMarkdown(strings.NewReader(tt.markdown))
+			if err != nil {
+				t.Fatalf("readMarkdown: %v", err)
+			}
+			if len(f.Questions) != 1 || !reflect.DeepEqual(f.Questions[0], tt.want) {
+				t.Errorf("questions = %+v, want %+v", f.Questions, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMarkdownErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{
+			name:     "no answer checked",
+			markdown: "## Q1\n\n- a\n- [ ] b\n",
+			want:     []string{`line 1: question "Q1" has no answer`},
+		},
+		{
+			name:     "no answers at all",
+			markdown: "## Q1\n\n## Q2\n\n- [x] a\n",
+			want:     []string{`line 1: question "Q1" has no answer`},
+		},
+		{
+			name:     "numbered list and checkboxes",
+			markdown: "## Q1\n\n- [x] a\n\n## Q2\n\n1. a\n- [x] b\n",
+			want:     []string{`line 5: question "Q2" mixes a numbered list with checkboxes`},
+		},
+		{
+			name:     "text after the answers",
+			markdown: "## Q1\n\n- [x] a\n- b\nmore text\n",
+			want:     []string{"line 5: text after the answers must be a > quote"},
+		},
+		{
+			name:     "code block after the answers",
+			markdown: "## Q1\n\n- [x] a\n- b\n```\ncode\n```\n",
+			want:     []string{"line 5: code blocks must come before the answers"},
+		},
+		{
+			name:     "several errors",
+			markdown: "---\ncourse_uuid: c\n---\n## Q1\n\n- a\n\n## Q2\n\n- [x] a\ntext\n",
+			want: []string{
+				`line 4: question "Q1" has no answer`,
+				"line 11: text after the answers must be a > quote",
+			},
+		},
+		{
+			name:     "front matter not closed",
+			markdown: "---\ncourse_uuid: c\n## Q1\n",
+			want:     []string{"front matter is not closed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMarkdown(strings.NewReader(tt.markdown))
+			if err == nil {
+				t.Fatalf("no error, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package dao
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openTestDB opens an empty database in a temporary file, without migrating it
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := OpenDatabase(DBConfig{DBPath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// schema returns the SQL of every table and index, leaving out the ones of SQLite
+func schema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY type, name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	statements := []string{}
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			t.Fatal(err)
+		}
+		statements = append(statements, stmt)
+	}
+	return statements
+}
+
+func TestMigrateUpDownUp(t *testing.T) {
+	db := openTestDB(t)
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := MigrateUp(db, 0)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	migrated := schema(t, db)
+
+	reverted, err := MigrateDown(db, len(migrations))
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if len(reverted) != len(migrations) || reverted[0].Version != migrations[len(migrations)-1].Version {
+		t.Fatalf("reverted %d migrations starting with %d, want all of them from the last", len(reverted), reverted[0].Version)
+	}
+	if got := schema(t, db); len(got) != 1 || !strings.Contains(got[0], "schema_migrations") {
+		t.Errorf("schema after reverting everything = %q, want only schema_migrations", got)
+	}
+
+	if _, err := MigrateUp(db, 0); err != nil {
+		t.Fatalf("MigrateUp again: %v", err)
+	}
+	if got := schema(t, db); !reflect.DeepEqual(got, migrated) {
+		t.Errorf("schema after up/down/up differs:\n%q\nwant\n%q", got, migrated)
+	}
+
+	// Stepping to a target version only applies what comes before it
+	if _, err := MigrateDown(db, 2); err != nil {
+		t.Fatal(err)
+	}
+	target := migrations[len(migrations)-2].Version
+	if applied, err := MigrateUp(db, target); err != nil || len(applied) != 1 || applied[0].Version != target {
+		t.Errorf("MigrateUp to %d applied %v, %v; want only %d", target, applied, err, target)
+	}
+}
+
+func TestMigrateBaselineSchema(t *testing.T) {
+	db := openTestDB(t)
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A database created before migrations existed, with the initial schema
+	// and answers but no schema_migrations table
+	answered := reviewStart
+	for _, stmt := range []string{
+		migrations[0].Up,
+		`INSERT INTO quizzes (id, course_uuid) VALUES (1, 'course')`,
+		`INSERT INTO questions (id, quiz_id, question_type_id, question_text, correct_answer) VALUES (1, 1, 2, 'Capital of France?', 'Paris')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, correct := range []bool{true, true, false} {
+		at := answered.AddDate(0, 0, i).Format(sqliteTimeFormat)
+		if _, err := db.Exec(`INSERT INTO user_answers (question_id, user_answer, is_correct, answered_at) VALUES (1, 'Paris', ?, ?)`, correct, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.Applied != (status.Version == 1) {
+			t.Errorf("migration %d applied = %v, want only the initial one", status.Version, status.Applied)
+		}
+	}
+
+	applied, err := MigrateUp(db, 0)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if len(applied) != len(migrations)-1 || applied[0].Version != 2 {
+		t.Errorf("applied %d migrations from %d, want every one after the initial", len(applied), applied[0].Version)
+	}
+
+	var answers int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM user_answers WHERE question_id = 1`).Scan(&answers); err != nil || answers != 3 {
+		t.Fatalf("%d answers kept (%v), want 3", answers, err)
+	}
+	// The question has no schedule yet, its state is rebuilt from the answers
+	now := answered.AddDate(0, 1, 0)
+	for _, scheduler := range []Scheduler{sm2Scheduler{}, fsrsScheduler{}, leitnerScheduler{}} {
+		got, err := getReviewState(db, scheduler, 1, now)
+		if err != nil {
+			t.Fatalf("%s: %v", scheduler.Name(), err)
+		}
+		want := scheduler.NewState(1, answered)
+		for i, quality := range []int{AnswerQuality(true), AnswerQuality(true), AnswerQuality(false)} {
+			want = scheduler.Review(want, quality, answered.AddDate(0, 0, i))
+		}
+		if !got.DueAt.Equal(want.DueAt) || got.Repetitions != want.Repetitions || got.Lapses != want.Lapses || got.Box != want.Box {
+			t.Errorf("%s: state %+v, want %+v", scheduler.Name(), got, want)
+		}
+	}
+}
+
+func TestMigrationLeavingOrphansFails(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := MigrateUp(db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans := Migration{Version: 999, Name: "orphans", Up: `INSERT INTO questions (quiz_id, question_type_id, question_text, correct_answer) VALUES (12345, 1, 'Orphan?', 'yes')`}
+	err := runMigration(db, orphans, orphans.Up, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, orphans.Version, orphans.Name)
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken foreign keys") {
+		t.Fatalf("error = %v, want the foreign key check to fail", err)
+	}
+
+	// Nothing of the migration is left
+	var questions, recorded int
+	db.QueryRow(`SELECT COUNT(*) FROM questions WHERE question_text = 'Orphan?'`).Scan(&questions)
+	db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = 999`).Scan(&recorded)
+	if questions != 0 || recorded != 0 {
+		t.Errorf("%d orphan questions and %d migration records left, want none", questions, recorded)
+	}
+
+	// Foreign keys are enforced again afterwards
+	if _, err := db.Exec(`INSERT INTO questions (quiz_id, question_type_id, question_text, correct_answer) VALUES (12345, 1, 'Orphan?', 'yes')`); err == nil {
+		t.Errorf("inserted an orphan question after the migration")
+	}
+}
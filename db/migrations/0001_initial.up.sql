@@ -0,0 +1,42 @@
+-- Question types
+CREATE TABLE question_types (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL
+);
+
+-- Insert question types
+INSERT INTO question_types (name) VALUES 
+    ('multiple_choice'),
+    ('fill_blank');
+
+-- Quizzes table (stores course UUID as reference)
+CREATE TABLE quizzes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_uuid TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(course_uuid) -- Ensure one quiz per course
+);
+
+-- Questions table
+CREATE TABLE questions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    quiz_id INTEGER NOT NULL,
+    question_type_id INTEGER NOT NULL,
+    question_text TEXT NOT NULL,
+    explanation TEXT,
+    answer_choices TEXT, -- JSON array for multiple choice, empty for fill_blank
+    correct_answer TEXT NOT NULL, -- The correct answer
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (quiz_id) REFERENCES quizzes(id) ON DELETE CASCADE,
+    FOREIGN KEY (question_type_id) REFERENCES question_types(id)
+);
+
+-- User answers
+CREATE TABLE user_answers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    question_id INTEGER NOT NULL,
+    user_answer TEXT NOT NULL,
+    is_correct BOOLEAN NOT NULL,
+    answered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user_answers DROP COLUMN scheduler;
+DROP TABLE IF EXISTS question_schedules;
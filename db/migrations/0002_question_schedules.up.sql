@@ -0,0 +1,20 @@
+-- Spaced repetition state, one row per reviewed question and scheduler
+CREATE TABLE question_schedules (
+    question_id INTEGER NOT NULL,
+    scheduler TEXT NOT NULL, -- sm2, fsrs or leitner
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    stability REAL NOT NULL DEFAULT 0,
+    difficulty REAL NOT NULL DEFAULT 0,
+    box INTEGER NOT NULL DEFAULT 0,
+    last_reviewed_at DATETIME,
+    due_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (question_id, scheduler),
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE
+);
+
+-- Scheduler active when the question was answered
+ALTER TABLE user_answers ADD COLUMN scheduler TEXT;
@@ -0,0 +1,2 @@
+-- JSON array of alternative answers for fill_blank
+ALTER TABLE questions ADD COLUMN accepted_answers TEXT;
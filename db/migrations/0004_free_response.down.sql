@@ -0,0 +1,13 @@
+-- Migrations run with foreign keys off, so dependent rows are deleted explicitly
+DELETE FROM question_schedules WHERE question_id IN (
+    SELECT id FROM questions WHERE question_type_id = (SELECT id FROM question_types WHERE name = 'free_response')
+);
+DELETE FROM user_answers WHERE question_id IN (
+    SELECT id FROM questions WHERE question_type_id = (SELECT id FROM question_types WHERE name = 'free_response')
+);
+DELETE FROM questions WHERE question_type_id = (SELECT id FROM question_types WHERE name = 'free_response');
+DELETE FROM question_types WHERE name = 'free_response';
+
+ALTER TABLE user_answers DROP COLUMN feedback;
+ALTER TABLE user_answers DROP COLUMN score;
+ALTER TABLE questions DROP COLUMN rubric;
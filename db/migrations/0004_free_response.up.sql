@@ -0,0 +1,10 @@
+INSERT INTO question_types (name) VALUES ('free_response');
+
+-- Grading criteria for free_response, correct_answer holds the reference answer
+ALTER TABLE questions ADD COLUMN rubric TEXT;
+
+-- 0 to 1, partial credit for graded answers
+ALTER TABLE user_answers ADD COLUMN score REAL;
+
+-- Grader feedback for free_response answers
+ALTER TABLE user_answers ADD COLUMN feedback TEXT;
@@ -0,0 +1,9 @@
+-- Migrations run with foreign keys off, so dependent rows are deleted explicitly
+DELETE FROM question_schedules WHERE question_id IN (
+    SELECT id FROM questions WHERE question_type_id IN (SELECT id FROM question_types WHERE name IN ('multi_select', 'ordering'))
+);
+DELETE FROM user_answers WHERE question_id IN (
+    SELECT id FROM questions WHERE question_type_id IN (SELECT id FROM question_types WHERE name IN ('multi_select', 'ordering'))
+);
+DELETE FROM questions WHERE question_type_id IN (SELECT id FROM question_types WHERE name IN ('multi_select', 'ordering'));
+DELETE FROM question_types WHERE name IN ('multi_select', 'ordering');
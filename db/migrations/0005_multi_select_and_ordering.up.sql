@@ -0,0 +1,5 @@
+-- multi_select: correct_answer is a JSON array of the correct choices
+-- ordering: correct_answer is a JSON array of the items in the right order
+INSERT INTO question_types (name) VALUES
+    ('multi_select'),
+    ('ordering');
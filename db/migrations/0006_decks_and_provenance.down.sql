@@ -0,0 +1,24 @@
+-- Only the default deck of each course fits in the old schema.
+-- Migrations run with foreign keys off, so dependent rows are deleted explicitly
+DELETE FROM question_schedules WHERE question_id IN (
+    SELECT id FROM questions WHERE quiz_id IN (SELECT id FROM quizzes WHERE deck != 'default')
+);
+DELETE FROM user_answers WHERE question_id IN (
+    SELECT id FROM questions WHERE quiz_id IN (SELECT id FROM quizzes WHERE deck != 'default')
+);
+DELETE FROM questions WHERE quiz_id IN (SELECT id FROM quizzes WHERE deck != 'default');
+
+CREATE TABLE quizzes_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_uuid TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(course_uuid) -- Ensure one quiz per course
+);
+INSERT INTO quizzes_old (id, course_uuid, created_at) SELECT id, course_uuid, created_at FROM quizzes WHERE deck = 'default';
+DROP TABLE quizzes;
+ALTER TABLE quizzes_old RENAME TO quizzes;
+
+ALTER TABLE questions DROP COLUMN chapter_slug;
+ALTER TABLE questions DROP COLUMN chapter_uuid;
+ALTER TABLE questions DROP COLUMN lesson_slug;
+ALTER TABLE questions DROP COLUMN lesson_uuid;
@@ -0,0 +1,19 @@
+-- Several decks per course (per chapter or custom), existing quizzes become
+-- the "default" deck. SQLite can't change a UNIQUE constraint in place, so
+-- the table is rebuilt.
+CREATE TABLE quizzes_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_uuid TEXT NOT NULL,
+    deck TEXT NOT NULL DEFAULT 'default',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(course_uuid, deck) -- Ensure one deck per name in a course
+);
+INSERT INTO quizzes_new (id, course_uuid, created_at) SELECT id, course_uuid, created_at FROM quizzes;
+DROP TABLE quizzes;
+ALTER TABLE quizzes_new RENAME TO quizzes;
+
+-- The lesson a question was generated from, and the chapter it belongs to
+ALTER TABLE questions ADD COLUMN lesson_uuid TEXT;
+ALTER TABLE questions ADD COLUMN lesson_slug TEXT;
+ALTER TABLE questions ADD COLUMN chapter_uuid TEXT;
+ALTER TABLE questions ADD COLUMN chapter_slug TEXT;
@@ -0,0 +1,2 @@
+-- Archived questions are kept with their history but left out of quizzes and reviews
+ALTER TABLE questions ADD COLUMN archived_at DATETIME;
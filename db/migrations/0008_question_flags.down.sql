@@ -0,0 +1,3 @@
+ALTER TABLE questions DROP COLUMN flagged_at;
+ALTER TABLE questions DROP COLUMN flag_note;
+ALTER TABLE questions DROP COLUMN flag_reason;
@@ -0,0 +1,5 @@
+-- Questions flagged during a quiz as wrong, ambiguous or too_easy, with an
+-- optional note; they are left out of quizzes and reviews until fixed
+ALTER TABLE questions ADD COLUMN flag_reason TEXT;
+ALTER TABLE questions ADD COLUMN flag_note TEXT;
+ALTER TABLE questions ADD COLUMN flagged_at DATETIME;
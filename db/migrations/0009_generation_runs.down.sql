@@ -0,0 +1,2 @@
+ALTER TABLE questions DROP COLUMN generation_run_id;
+DROP TABLE generation_runs;
@@ -0,0 +1,21 @@
+-- Every call made to a language model, to account for its cost and to parse
+-- its response again when that failed
+CREATE TABLE generation_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL, -- generate, regenerate or grade
+    course_uuid TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    prompt_hash TEXT NOT NULL,
+    raw_response TEXT NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cost REAL NOT NULL DEFAULT 0, -- estimated, in USD
+    lessons TEXT NOT NULL DEFAULT '[]', -- JSON array of the lessons in the prompt, by number
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_generation_runs_course ON generation_runs(course_uuid);
+
+-- The run a question was generated by. Not a foreign key, which SQLite
+-- couldn't drop in the down migration
+ALTER TABLE questions ADD COLUMN generation_run_id INTEGER;
@@ -0,0 +1,3 @@
+DROP TABLE lessons;
+DROP TABLE chapters;
+DROP TABLE courses;
@@ -0,0 +1,33 @@
+-- Local copy of the courses questions are generated from, downloaded with
+-- quiz-mgmt download_course
+CREATE TABLE courses (
+    uuid TEXT PRIMARY KEY,
+    slug TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE chapters (
+    uuid TEXT PRIMARY KEY,
+    course_uuid TEXT NOT NULL REFERENCES courses(uuid) ON DELETE CASCADE,
+    slug TEXT NOT NULL,
+    title TEXT NOT NULL,
+    position INTEGER NOT NULL -- from 0, in the order of the course
+);
+CREATE INDEX idx_chapters_course ON chapters(course_uuid, position);
+
+CREATE TABLE lessons (
+    uuid TEXT PRIMARY KEY,
+    chapter_uuid TEXT NOT NULL REFERENCES chapters(uuid) ON DELETE CASCADE,
+    course_uuid TEXT NOT NULL REFERENCES courses(uuid) ON DELETE CASCADE,
+    slug TEXT NOT NULL,
+    title TEXT NOT NULL,
+    position INTEGER NOT NULL, -- from 0, in the order of the chapter
+    content TEXT NOT NULL DEFAULT '', -- the readme, empty until downloaded
+    content_hash TEXT NOT NULL DEFAULT '', -- sha256 of the content
+    listing_hash TEXT NOT NULL DEFAULT '', -- sha256 of the lesson in the course listing, to tell when to download it again
+    fetched_at DATETIME -- when the content was downloaded
+);
+CREATE INDEX idx_lessons_chapter ON lessons(chapter_uuid, position);
+CREATE INDEX idx_lessons_course ON lessons(course_uuid);
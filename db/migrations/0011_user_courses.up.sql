@@ -0,0 +1,11 @@
+-- The course list of each user, cached by bootdev quiz to work offline
+CREATE TABLE user_courses (
+    user_handle TEXT NOT NULL,
+    uuid TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    title TEXT NOT NULL,
+    completed_at TEXT NOT NULL DEFAULT '', -- as returned by the API, empty until completed
+    position INTEGER NOT NULL, -- in the order of the API
+    cached_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_handle, uuid)
+);
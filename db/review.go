@@ -0,0 +1,54 @@
+package dao
+
+import "database/sql"
+
+// GetReviewSession collects the due questions of every course quiz, interleaves
+// them so consecutive questions come from different courses, and caps the
+// session at size questions (no cap if size <= 0). It returns nil if nothing is due.
+func GetReviewSession(db *sql.DB, size int) (*Quiz, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	questions, err := getDueQuestions(db, QuestionFilter{})
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, nil
+	}
+
+	questions = interleaveByCourse(questions)
+	if size > 0 && len(questions) > size {
+		questions = questions[:size]
+	}
+	return &Quiz{Questions: questions}, nil
+}
+
+// interleaveByCourse shuffles the questions of each course and deals them out
+// round-robin, so a capped session still covers as many courses as possible
+func interleaveByCourse(questions []Question) []Question {
+	var courses []string
+	byCourse := map[string][]Question{}
+	for _, q := range questions {
+		if _, ok := byCourse[q.CourseUUID]; !ok {
+			courses = append(courses, q.CourseUUID)
+		}
+		byCourse[q.CourseUUID] = append(byCourse[q.CourseUUID], q)
+	}
+	shuffle(courses)
+	for _, course := range courses {
+		shuffle(byCourse[course])
+	}
+
+	interleaved := make([]Question, 0, len(questions))
+	for round := 0; len(interleaved) < len(questions); round++ {
+		for _, course := range courses {
+			if round < len(byCourse[course]) {
+				interleaved = append(interleaved, byCourse[course][round])
+			}
+		}
+	}
+	return interleaved
+}
@@ -0,0 +1,156 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GenerationRun is a call made to a language model
+type GenerationRun struct {
+	ID int64
+	// Kind is what the call was for: generate, regenerate or grade
+	Kind         string
+	CourseUUID   string
+	Provider     string
+	Model        string
+	PromptHash   string
+	RawResponse  string
+	InputTokens  int64
+	OutputTokens int64
+	// Cost is estimated from the token counts, in USD
+	Cost float64
+	// Lessons are the lessons in the prompt, numbered from 1 in the same order
+	Lessons   []LessonRef
+	CreatedAt time.Time
+}
+
+// LessonRef identifies the lesson a question is generated from, and its chapter
+type LessonRef struct {
+	LessonUUID  string `json:"lesson_uuid"`
+	LessonSlug  string `json:"lesson_slug,omitempty"`
+	ChapterUUID string `json:"chapter_uuid,omitempty"`
+	ChapterSlug string `json:"chapter_slug,omitempty"`
+}
+
+// CreateGenerationRun records a call made to a language model and sets its ID
+func CreateGenerationRun(db *sql.DB, run *GenerationRun) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	lessons, err := json.Marshal(run.Lessons)
+	if err != nil {
+		return err
+	}
+	if run.Lessons == nil {
+		lessons = []byte("[]")
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO generation_runs (kind, course_uuid, provider, model, prompt_hash, raw_response, input_tokens, output_tokens, cost, lessons)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.Kind, run.CourseUUID, run.Provider, run.Model, run.PromptHash, run.RawResponse, run.InputTokens, run.OutputTokens, run.Cost, string(lessons))
+	if err != nil {
+		return fmt.Errorf("failed to record generation run: %w", err)
+	}
+	run.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetGenerationRun returns a recorded call made to a language model
+func GetGenerationRun(db *sql.DB, id int64) (*GenerationRun, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	var run GenerationRun
+	var lessons string
+	err := db.QueryRow(`
+		SELECT id, kind, course_uuid, provider, model, prompt_hash, raw_response, input_tokens, output_tokens, cost, lessons, created_at
+		FROM generation_runs
+		WHERE id = ?
+	`, id).Scan(&run.ID, &run.Kind, &run.CourseUUID, &run.Provider, &run.Model, &run.PromptHash, &run.RawResponse,
+		&run.InputTokens, &run.OutputTokens, &run.Cost, &lessons, &run.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("generation run %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting generation run %d: %w", id, err)
+	}
+	if err := json.Unmarshal([]byte(lessons), &run.Lessons); err != nil {
+		return nil, fmt.Errorf("invalid lessons in generation run %d: %w", id, err)
+	}
+	return &run, nil
+}
+
+// SetGenerationRun links a question to the generation run that produced it
+func SetGenerationRun(db *sql.DB, questionID, runID int64) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	_, err := db.Exec(`UPDATE questions SET generation_run_id = NULLIF(?, 0) WHERE id = ?`, runID, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to set the generation run of question %d: %w", questionID, err)
+	}
+	return nil
+}
+
+// Usage sums up the calls made to language models for a course or a month
+type Usage struct {
+	Key          string
+	Calls        int
+	InputTokens  int64
+	OutputTokens int64
+	Cost         float64
+	// Questions is how many of the questions they generated are still in the bank
+	Questions int
+}
+
+// GetUsageByCourse sums up the calls made for each course, optionally only one
+func GetUsageByCourse(db *sql.DB, courseUUID string) ([]Usage, error) {
+	return getUsage(db, "r.course_uuid", courseUUID)
+}
+
+// GetUsageByMonth sums up the calls made each month, as YYYY-MM, for every
+// course or only one
+func GetUsageByMonth(db *sql.DB, courseUUID string) ([]Usage, error) {
+	return getUsage(db, "strftime('%Y-%m', r.created_at)", courseUUID)
+}
+
+func getUsage(db *sql.DB, key string, courseUUID string) ([]Usage, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	query := `
+		SELECT ` + key + ` AS usage_key, COUNT(*), SUM(r.input_tokens), SUM(r.output_tokens), SUM(r.cost),
+			SUM((SELECT COUNT(*) FROM questions q WHERE q.generation_run_id = r.id))
+		FROM generation_runs r
+		WHERE (? = '' OR r.course_uuid = ?)
+		GROUP BY usage_key
+		ORDER BY usage_key ASC
+	`
+	rows, err := db.Query(query, courseUUID, courseUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []Usage
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Key, &u.Calls, &u.InputTokens, &u.OutputTokens, &u.Cost, &u.Questions); err != nil {
+			return nil, fmt.Errorf("error scanning usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
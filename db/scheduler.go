@@ -0,0 +1,290 @@
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// sqliteTimeFormat matches the format SQLite uses for CURRENT_TIMESTAMP and datetime('now')
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// DefaultScheduler is used when the quiz.scheduler config key is not set
+const DefaultScheduler = "sm2"
+
+// ReviewState holds the spaced repetition state of a question for one scheduler.
+// Each scheduler only uses the fields relevant to its algorithm.
+type ReviewState struct {
+	QuestionID     int64
+	EaseFactor     float64 // SM-2
+	IntervalDays   int
+	Repetitions    int
+	Lapses         int
+	Stability      float64 // FSRS
+	Difficulty     float64 // FSRS
+	Box            int     // Leitner
+	LastReviewedAt time.Time
+	DueAt          time.Time
+}
+
+// IsNew reports whether the question has never been reviewed
+func (s ReviewState) IsNew() bool {
+	return s.Repetitions == 0 && s.Lapses == 0 && s.LastReviewedAt.IsZero()
+}
+
+// Scheduler decides when a question should be reviewed again
+type Scheduler interface {
+	Name() string
+	// NewState returns the state of a question that has never been reviewed
+	NewState(questionID int64, now time.Time) ReviewState
+	// Review returns the updated state after an answer with the given quality (0-5)
+	Review(state ReviewState, quality int, now time.Time) ReviewState
+}
+
+var schedulers = map[string]Scheduler{
+	"sm2":     sm2Scheduler{},
+	"fsrs":    fsrsScheduler{},
+	"leitner": leitnerScheduler{},
+}
+
+// SchedulerNames returns the names of all available schedulers
+func SchedulerNames() []string {
+	names := make([]string, 0, len(schedulers))
+	for name := range schedulers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetScheduler returns the scheduler registered under name
+func GetScheduler(name string) (Scheduler, error) {
+	s, ok := schedulers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheduler %q (available: %v)", name, SchedulerNames())
+	}
+	return s, nil
+}
+
+// ActiveScheduler returns the scheduler selected with the quiz.scheduler config key
+func ActiveScheduler() (Scheduler, error) {
+	name := viper.GetString("quiz.scheduler")
+	if name == "" {
+		name = DefaultScheduler
+	}
+	return GetScheduler(name)
+}
+
+// AnswerQuality maps an answer to an SM-2 style quality grade (0-5)
+func AnswerQuality(isCorrect bool) int {
+	if isCorrect {
+		return 4
+	}
+	return 1
+}
+
+// getReviewState loads the state of a question for the given scheduler.
+// Questions the scheduler has not seen yet get their state rebuilt from the
+// answer history, so switching schedulers does not make everything due again.
+func getReviewState(db *sql.DB, scheduler Scheduler, questionID int64, now time.Time) (ReviewState, error) {
+	query := `
+		SELECT ease_factor, interval_days, repetitions, lapses, stability, difficulty, box, last_reviewed_at, due_at
+		FROM question_schedules
+		WHERE question_id = ? AND scheduler = ?
+	`
+	s := ReviewState{QuestionID: questionID}
+	var lastReviewedAt sql.NullTime
+	err := db.QueryRow(query, questionID, scheduler.Name()).Scan(
+		&s.EaseFactor,
+		&s.IntervalDays,
+		&s.Repetitions,
+		&s.Lapses,
+		&s.Stability,
+		&s.Difficulty,
+		&s.Box,
+		&lastReviewedAt,
+		&s.DueAt,
+	)
+	if err == sql.ErrNoRows {
+		return replayHistory(db, scheduler, questionID, now)
+	}
+	if err != nil {
+		return s, fmt.Errorf("error getting schedule for question %d: %w", questionID, err)
+	}
+	s.LastReviewedAt = lastReviewedAt.Time
+	return s, nil
+}
+
+// replayHistory rebuilds the state of a question by feeding its past answers to the scheduler
+func replayHistory(db *sql.DB, scheduler Scheduler, questionID int64, now time.Time) (ReviewState, error) {
+	query := `
+		SELECT is_correct, answered_at
+		FROM user_answers
+		WHERE question_id = ?
+		ORDER BY answered_at ASC, id ASC
+	`
+	rows, err := db.Query(query, questionID)
+	if err != nil {
+		return ReviewState{}, fmt.Errorf("error getting answer history for question %d: %w", questionID, err)
+	}
+	defer rows.Close()
+
+	var s *ReviewState
+	for rows.Next() {
+		var isCorrect bool
+		var answeredAt time.Time
+		if err := rows.Scan(&isCorrect, &answeredAt); err != nil {
+			return ReviewState{}, fmt.Errorf("error scanning answer history: %w", err)
+		}
+		if s == nil {
+			initial := scheduler.NewState(questionID, answeredAt)
+			s = &initial
+		}
+		*s = scheduler.Review(*s, AnswerQuality(isCorrect), answeredAt)
+	}
+	if err := rows.Err(); err != nil {
+		return ReviewState{}, err
+	}
+	if s == nil {
+		return scheduler.NewState(questionID, now), nil
+	}
+	return *s, nil
+}
+
+// filterDue drops the questions the scheduler has no state for yet, but whose
+// answer history says they are not due
+func filterDue(db *sql.DB, scheduler Scheduler, questions []Question, unscheduled map[int64]bool, now time.Time) ([]Question, error) {
+	due := []Question{}
+	for _, q := range questions {
+		if unscheduled[q.ID] {
+			s, err := getReviewState(db, scheduler, q.ID, now)
+			if err != nil {
+				return nil, err
+			}
+			if s.DueAt.After(now) {
+				continue
+			}
+		}
+		due = append(due, q)
+	}
+	return due, nil
+}
+
+func saveReviewState(db *sql.DB, scheduler Scheduler, s ReviewState) error {
+	stmt := `
+	INSERT INTO question_schedules (
+		question_id, scheduler, ease_factor, interval_days, repetitions, lapses,
+		stability, difficulty, box, last_reviewed_at, due_at, updated_at
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT (question_id, scheduler) DO UPDATE SET
+		ease_factor = excluded.ease_factor,
+		interval_days = excluded.interval_days,
+		repetitions = excluded.repetitions,
+		lapses = excluded.lapses,
+		stability = excluded.stability,
+		difficulty = excluded.difficulty,
+		box = excluded.box,
+		last_reviewed_at = excluded.last_reviewed_at,
+		due_at = excluded.due_at,
+		updated_at = excluded.updated_at
+	`
+	_, err := db.Exec(stmt,
+		s.QuestionID,
+		scheduler.Name(),
+		s.EaseFactor,
+		s.IntervalDays,
+		s.Repetitions,
+		s.Lapses,
+		s.Stability,
+		s.Difficulty,
+		s.Box,
+		s.LastReviewedAt.UTC().Format(sqliteTimeFormat),
+		s.DueAt.UTC().Format(sqliteTimeFormat),
+	)
+	if err != nil {
+		return fmt.Errorf("error updating schedule for question %d: %w", s.QuestionID, err)
+	}
+	return nil
+}
+
+// ReviewQuestion updates the schedule of a question after it has been answered,
+// using the scheduler selected in the config. Call it before MarkQuestionAnswered,
+// otherwise the answer is counted twice when the state is rebuilt from history.
+func ReviewQuestion(db *sql.DB, questionID int64, quality int) error {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	scheduler, err := ActiveScheduler()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	s, err := getReviewState(db, scheduler, questionID, now)
+	if err != nil {
+		return err
+	}
+	return saveReviewState(db, scheduler, scheduler.Review(s, quality, now))
+}
+
+// RetentionStats summarizes how well answers were recalled under one scheduler
+type RetentionStats struct {
+	Scheduler         string
+	Reviews           int
+	Recalled          int
+	RetentionRate     float64
+	AvgIntervalDays   float64
+	QuestionsReviewed int
+}
+
+// GetRetentionStats compares schedulers using the answer history. Only repeat
+// reviews count, since the first answer to a question says nothing about retention.
+func GetRetentionStats(db *sql.DB) ([]RetentionStats, error) {
+	query := `
+		SELECT
+			ua.scheduler,
+			COUNT(*) as reviews,
+			SUM(CASE WHEN ua.is_correct = 1 THEN 1 ELSE 0 END) as recalled,
+			AVG(julianday(ua.answered_at) - (
+				SELECT MAX(julianday(prev.answered_at))
+				FROM user_answers prev
+				WHERE prev.question_id = ua.question_id AND prev.id < ua.id
+			)) as avg_interval,
+			COUNT(DISTINCT ua.question_id) as questions
+		FROM user_answers ua
+		WHERE ua.scheduler IS NOT NULL
+		  AND EXISTS (
+			  SELECT 1 FROM user_answers prev
+			  WHERE prev.question_id = ua.question_id AND prev.id < ua.id
+		  )
+		GROUP BY ua.scheduler
+		ORDER BY ua.scheduler
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying retention stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []RetentionStats
+	for rows.Next() {
+		var stat RetentionStats
+		err := rows.Scan(&stat.Scheduler, &stat.Reviews, &stat.Recalled, &stat.AvgIntervalDays, &stat.QuestionsReviewed)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning retention stats: %w", err)
+		}
+		if stat.Reviews > 0 {
+			stat.RetentionRate = float64(stat.Recalled) / float64(stat.Reviews) * 100
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+)
+
+// Demo quizzes live in seeds/ as plain SQL, one file per pack
+//
+//go:embed seeds/*.sql
+var seedFiles embed.FS
+
+// SeedPack is a demo quiz that can be loaded into the database without
+// generating questions
+type SeedPack struct {
+	Name        string
+	CourseUUID  string
+	Description string
+}
+
+// DemoSeedPack is loaded when the user asks for a mock quiz
+const DemoSeedPack = "total-recall"
+
+var seedPacks = []SeedPack{
+	{Name: "capitals", CourseUUID: "european-capitals-uuid", Description: "European capitals"},
+	{Name: "rdbms", CourseUUID: "rdbms-fundamentals-uuid", Description: "Relational database fundamentals"},
+	{Name: "memes", CourseUUID: "meme-knowledge-uuid", Description: "Internet memes"},
+	{Name: "total-recall", CourseUUID: "total-recall-uuid", Description: "Total Recall (1990)"},
+}
+
+// SeedPacks returns all available demo packs
+func SeedPacks() []SeedPack {
+	return seedPacks
+}
+
+// GetSeedPack returns the demo pack registered under name
+func GetSeedPack(name string) (SeedPack, error) {
+	names := make([]string, 0, len(seedPacks))
+	for _, pack := range seedPacks {
+		if pack.Name == name {
+			return pack, nil
+		}
+		names = append(names, pack.Name)
+	}
+	return SeedPack{}, fmt.Errorf("unknown seed pack %q (available: %v)", name, names)
+}
+
+// IsSeeded reports whether the pack's quiz is in the database. Packs are
+// loaded into the default deck of their course.
+func IsSeeded(db *sql.DB, pack SeedPack) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM quizzes WHERE course_uuid = ? AND deck = ?`, pack.CourseUUID, DefaultDeck).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error checking seed pack %s: %w", pack.Name, err)
+	}
+	return count > 0, nil
+}
+
+// Seed loads a demo pack. It returns false if the pack was already loaded.
+func Seed(db *sql.DB, pack SeedPack) (bool, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	seeded, err := IsSeeded(db, pack)
+	if err != nil || seeded {
+		return false, err
+	}
+
+	script, err := seedFiles.ReadFile(path.Join("seeds", pack.Name+".sql"))
+	if err != nil {
+		return false, fmt.Errorf("failed to read seed pack %s: %w", pack.Name, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(string(script)); err != nil {
+		return false, fmt.Errorf("failed to load seed pack %s: %w", pack.Name, err)
+	}
+	return true, tx.Commit()
+}
+
+// Unseed removes a demo pack along with its answer history. It returns false
+// if the pack was not loaded.
+func Unseed(db *sql.DB, pack SeedPack) (bool, error) {
+	if db == nil {
+		db = getDefaultDB()
+	}
+	defer db.Close()
+
+	result, err := db.Exec(`DELETE FROM quizzes WHERE course_uuid = ? AND deck = ?`, pack.CourseUUID, DefaultDeck)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove seed pack %s: %w", pack.Name, err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return removed > 0, nil
+}
@@ -0,0 +1,26 @@
+-- European Capitals demo quiz - 10 questions
+INSERT INTO quizzes (course_uuid) VALUES ('european-capitals-uuid');
+
+INSERT INTO questions (quiz_id, question_type_id, question_text, explanation, answer_choices, correct_answer)
+SELECT quizzes.id, question_types.id, v.column2, v.column3, v.column4, v.column5
+FROM (VALUES
+    ('multiple_choice', 'What is the capital of France?', 'Paris is the capital and most populous city of France.',
+     '["Paris", "Lyon", "Marseille", "Nice"]', 'Paris'),
+    ('multiple_choice', 'What is the capital of Germany?', 'Berlin is the capital and largest city of Germany.',
+     '["Munich", "Hamburg", "Berlin", "Frankfurt"]', 'Berlin'),
+    ('fill_blank', 'The capital of Italy is _____.', 'Rome has been the capital of Italy since 1871.', '', 'Rome'),
+    ('multiple_choice', 'What is the capital of Spain?', 'Madrid is the capital and most populous city of Spain.',
+     '["Barcelona", "Madrid", "Valencia", "Seville"]', 'Madrid'),
+    ('multiple_choice', 'What is the capital of Portugal?', 'Lisbon is the capital and largest city of Portugal.',
+     '["Porto", "Braga", "Lisbon", "Coimbra"]', 'Lisbon'),
+    ('fill_blank', 'The capital of Netherlands is _____.', 'Amsterdam is the capital, though The Hague is the seat of government.', '', 'Amsterdam'),
+    ('multiple_choice', 'What is the capital of Poland?', 'Warsaw is the capital and largest city of Poland.',
+     '["Krakow", "Warsaw", "Gdansk", "Wroclaw"]', 'Warsaw'),
+    ('multiple_choice', 'What is the capital of Sweden?', 'Stockholm is the capital of Sweden.',
+     '["Stockholm", "Gothenburg", "Malmö", "Uppsala"]', 'Stockholm'),
+    ('fill_blank', 'The capital of Greece is _____.', 'Athens is the capital and largest city of Greece.', '', 'Athens'),
+    ('multiple_choice', 'What is the capital of Czech Republic?', 'Prague is the capital and largest city of Czech Republic.',
+     '["Brno", "Prague", "Ostrava", "Plzen"]', 'Prague')
+) v
+JOIN quizzes ON quizzes.course_uuid = 'european-capitals-uuid' AND quizzes.deck = 'default'
+JOIN question_types ON question_types.name = v.column1;
@@ -0,0 +1,25 @@
+-- Meme Knowledge demo quiz - 10 questions
+INSERT INTO quizzes (course_uuid) VALUES ('meme-knowledge-uuid');
+
+INSERT INTO questions (quiz_id, question_type_id, question_text, explanation, answer_choices, correct_answer)
+SELECT quizzes.id, question_types.id, v.column2, v.column3, v.column4, v.column5
+FROM (VALUES
+    ('multiple_choice', 'Which meme features a dog surrounded by fire saying "This is fine"?', 'This meme represents staying calm in chaotic situations.',
+     '["Grumpy Cat", "Distracted Boyfriend", "This is Fine Dog", "Drake Pointing"]', 'This is Fine Dog'),
+    ('fill_blank', 'Complete the meme: "One does not simply _____ into Mordor"', 'This Boromir meme is from Lord of the Rings.', '', 'walk'),
+    ('multiple_choice', 'What does "Based" mean in internet slang?', 'Based means being authentic and not caring about others opinions.',
+     '["Fake", "Authentic and not caring about others opinions", "Located at a base", "Mathematical foundation"]', 'Authentic and not caring about others opinions'),
+    ('multiple_choice', 'Which meme shows a man looking back at another woman while his girlfriend looks disapproving?', 'This meme represents being tempted by alternatives.',
+     '["Distracted Boyfriend", "Hide the Pain Harold", "Woman Yelling at Cat", "Drake Pointing"]', 'Distracted Boyfriend'),
+    ('fill_blank', 'Complete the phrase: "It''s over 9000!" comes from _____.', 'This famous line is from the Dragon Ball Z anime.', '', 'Dragon Ball Z'),
+    ('multiple_choice', 'What does "sus" mean in Among Us and internet culture?', 'Sus is short for suspicious, popularized by Among Us.',
+     '["Super", "Suspicious", "Support", "System"]', 'Suspicious'),
+    ('multiple_choice', 'Which meme features a cat at a dinner table with vegetables?', 'Woman Yelling at Cat became popular for expressing disagreements.',
+     '["Keyboard Cat", "Nyan Cat", "Woman Yelling at Cat", "Business Cat"]', 'Woman Yelling at Cat'),
+    ('fill_blank', 'The "_____ Guy" meme features a man with a forced smile hiding pain.', 'Hide the Pain Harold represents masking discomfort with a smile.', '', 'Hide the Pain'),
+    ('multiple_choice', 'What does "POV" stand for in TikTok and social media?', 'POV means Point of View, used to set up scenarios.',
+     '["Point of View", "Power of Victory", "Post of Value", "Part of Video"]', 'Point of View'),
+    ('fill_blank', 'Complete the meme: "But wait, there''s _____!"', 'This phrase became popular from infomercials and is used ironically.', '', 'more')
+) v
+JOIN quizzes ON quizzes.course_uuid = 'meme-knowledge-uuid' AND quizzes.deck = 'default'
+JOIN question_types ON question_types.name = v.column1;
@@ -0,0 +1,26 @@
+-- RDBMS Fundamentals demo quiz - 10 questions
+INSERT INTO quizzes (course_uuid) VALUES ('rdbms-fundamentals-uuid');
+
+INSERT INTO questions (quiz_id, question_type_id, question_text, explanation, answer_choices, correct_answer)
+SELECT quizzes.id, question_types.id, v.column2, v.column3, v.column4, v.column5
+FROM (VALUES
+    ('multiple_choice', 'What does SQL stand for?', 'SQL is a domain-specific language used in programming for managing relational databases.',
+     '["Structured Query Language", "Simple Query Language", "Standard Query Language", "Sequential Query Language"]', 'Structured Query Language'),
+    ('multiple_choice', 'Which SQL command is used to retrieve data?', 'SELECT is the primary command for querying data in SQL.',
+     '["GET", "FETCH", "SELECT", "RETRIEVE"]', 'SELECT'),
+    ('fill_blank', 'A _____ key uniquely identifies each record in a database table.', 'Primary keys ensure each row can be uniquely identified.', '', 'primary'),
+    ('multiple_choice', 'What is a foreign key?', 'A foreign key is a field that refers to the primary key in another table.',
+     '["A key from another country", "A field that refers to the primary key in another table", "An encrypted key", "A backup key"]', 'A field that refers to the primary key in another table'),
+    ('multiple_choice', 'Which SQL command is used to add new data?', 'INSERT is used to add new records to a table.',
+     '["ADD", "INSERT", "CREATE", "PUT"]', 'INSERT'),
+    ('fill_blank', 'The process of organizing data to reduce redundancy is called _____.', 'Normalization reduces data duplication and improves integrity.', '', 'normalization'),
+    ('multiple_choice', 'What does ACID stand for in database transactions?', 'ACID properties ensure reliable database transactions.',
+     '["Atomic, Consistent, Isolated, Durable", "All, Create, Insert, Delete", "Always, Complete, Individual, Done", "Accurate, Complete, Independent, Definite"]', 'Atomic, Consistent, Isolated, Durable'),
+    ('multiple_choice', 'Which SQL clause is used to filter results?', 'WHERE clause filters rows based on specified conditions.',
+     '["FILTER", "WHERE", "HAVING", "IF"]', 'WHERE'),
+    ('fill_blank', 'A database _____ is a collection of related tables.', 'A schema defines the structure and organization of a database.', '', 'schema'),
+    ('multiple_choice', 'What is an index in a database?', 'An index improves query performance by creating shortcuts to data.',
+     '["A table of contents", "A data structure that improves query performance", "A backup copy", "A user permission"]', 'A data structure that improves query performance')
+) v
+JOIN quizzes ON quizzes.course_uuid = 'rdbms-fundamentals-uuid' AND quizzes.deck = 'default'
+JOIN question_types ON question_types.name = v.column1;
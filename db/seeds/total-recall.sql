@@ -0,0 +1,39 @@
+-- Total Recall (1990) demo quiz - 15 questions
+INSERT INTO quizzes (course_uuid) VALUES ('total-recall-uuid');
+
+INSERT INTO questions (quiz_id, question_type_id, question_text, explanation, answer_choices, correct_answer)
+SELECT quizzes.id, question_types.id, v.column2, v.column3, v.column4, v.column5
+FROM (VALUES
+    ('multiple_choice', 'What''s the name of Arnold''s character who may or may not be dreaming?', 'Douglas Quaid is our possibly-dreaming protagonist.',
+     '["Douglas Quaid", "John Matrix", "Dutch Schaefer", "Ben Richards"]', 'Douglas Quaid'),
+    ('multiple_choice', 'On which planet does most of the action take place?', 'Mars is where all the mutant fun happens!',
+     '["Venus", "Mars", "Jupiter", "Uranus"]', 'Mars'),
+    ('multiple_choice', 'What does Quaid originally do for a living?', 'He''s just a regular construction worker... or is he?',
+     '["Construction worker", "Secret agent", "Taxi driver", "Chef"]', 'Construction worker'),
+    ('multiple_choice', 'What''s the name of the memory implantation company?', 'Rekall - where your wildest dreams become fake memories!',
+     '["Rekall", "MemCorp", "DreamTech", "MindBenders"]', 'Rekall'),
+    ('multiple_choice', 'Who plays Quaid''s wife Lori?', 'Sharon Stone before she became famous for crossing her legs.',
+     '["Sharon Stone", "Jamie Lee Curtis", "Sigourney Weaver", "Linda Hamilton"]', 'Sharon Stone'),
+    ('multiple_choice', 'What''s special about the three-breasted woman?', 'She''s got... well, three breasts. Mars really changed the dress code.',
+     '["She has three arms", "She has three breasts", "She has three eyes", "She has three heads"]', 'She has three breasts'),
+    ('multiple_choice', 'What happens when you remove your helmet on Mars?', 'Your eyes pop out like a cartoon character - very practical!',
+     '["You turn blue", "Your eyes bulge out", "You grow gills", "You become invisible"]', 'Your eyes bulge out'),
+    ('multiple_choice', 'Who''s the leader of the Mars resistance?', 'Kuato - the guy who really puts the "guts" in leadership.',
+     '["Kuato", "Cohaagen", "Benny", "Richter"]', 'Kuato'),
+    ('multiple_choice', 'Where does Kuato literally live?', 'In his brother''s stomach - talk about family closeness!',
+     '["In a cave", "In his brother''s stomach", "Underground", "In a spaceship"]', 'In his brother''s stomach'),
+    ('multiple_choice', 'What does Benny drive around Mars?', 'A taxi cab - because even on Mars, you need reliable transportation.',
+     '["A hover car", "A taxi cab", "A motorcycle", "A bulldozer"]', 'A taxi cab'),
+    ('multiple_choice', 'What famous Arnold line does he say before shooting someone?', 'Consider that a divorce - harsh but effective!',
+     '["I''ll be back", "Consider that a divorce", "Get to the chopper", "Come with me if you want to live"]', 'Consider that a divorce'),
+    ('multiple_choice', 'What color is the sky on Mars in the movie?', 'Red, like a permanent sunset (or a really bad air quality day).',
+     '["Blue", "Purple", "Green", "Red"]', 'Red'),
+    ('multiple_choice', 'How does Quaid disguise himself?', 'As a fat woman - because nothing says "subtle" like Arnold in drag.',
+     '["As a robot", "As a fat woman", "As an alien", "As a child"]', 'As a fat woman'),
+    ('multiple_choice', 'What starts the Mars atmosphere machine?', 'An alien reactor - because aliens always leave the best toys behind.',
+     '["A nuclear bomb", "An alien reactor", "Solar panels", "Wind power"]', 'An alien reactor'),
+    ('multiple_choice', 'What''s the twist about whether it''s all a dream?', 'The movie never tells us - it''s the ultimate "choose your own adventure."',
+     '["It''s definitely real", "It''s definitely a dream", "We never find out for sure", "It''s a simulation"]', 'We never find out for sure')
+) v
+JOIN quizzes ON quizzes.course_uuid = 'total-recall-uuid' AND quizzes.deck = 'default'
+JOIN question_types ON question_types.name = v.column1;
@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultEaseFactor = 2.5
+	minEaseFactor     = 1.3
+)
+
+// sm2Scheduler implements the SuperMemo SM-2 algorithm
+type sm2Scheduler struct{}
+
+func (sm2Scheduler) Name() string { return "sm2" }
+
+func (sm2Scheduler) NewState(questionID int64, now time.Time) ReviewState {
+	return ReviewState{
+		QuestionID: questionID,
+		EaseFactor: defaultEaseFactor,
+		DueAt:      now,
+	}
+}
+
+func (sm2Scheduler) Review(s ReviewState, quality int, now time.Time) ReviewState {
+	quality = max(0, min(5, quality))
+
+	if quality < 3 {
+		// Failed recall: start the repetitions over
+		s.Repetitions = 0
+		s.IntervalDays = 1
+		s.Lapses++
+	} else {
+		switch s.Repetitions {
+		case 0:
+			s.IntervalDays = 1
+		case 1:
+			s.IntervalDays = 6
+		default:
+			s.IntervalDays = int(math.Round(float64(s.IntervalDays) * s.EaseFactor))
+		}
+		s.Repetitions++
+	}
+
+	q := float64(5 - quality)
+	s.EaseFactor = max(minEaseFactor, s.EaseFactor+(0.1-q*(0.08+q*0.02)))
+	s.LastReviewedAt = now
+	s.DueAt = now.AddDate(0, 0, s.IntervalDays)
+	return s
+}
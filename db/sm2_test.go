@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var reviewStart = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// reviewOnDue answers a question with each quality in turn, every time on the
+// day it is due, and returns the state after each answer
+func reviewOnDue(s Scheduler, qualities ...int) []ReviewState {
+	state := s.NewState(1, reviewStart)
+	states := []ReviewState{}
+	for _, quality := range qualities {
+		state = s.Review(state, quality, state.DueAt)
+		states = append(states, state)
+	}
+	return states
+}
+
+func TestSM2EaseFactor(t *testing.T) {
+	// EF' = EF + (0.1 - (5-q) * (0.08 + (5-q) * 0.02))
+	tests := []struct {
+		quality int
+		want    float64
+	}{
+		{5, 2.6},
+		{4, 2.5},
+		{3, 2.36},
+		{2, 2.18},
+		{1, 1.96},
+		{0, 1.7},
+		// Out of range qualities are clamped
+		{7, 2.6},
+		{-1, 1.7},
+	}
+	for _, tt := range tests {
+		s := sm2Scheduler{}.Review(sm2Scheduler{}.NewState(1, reviewStart), tt.quality, reviewStart)
+		if math.Abs(s.EaseFactor-tt.want) > 1e-9 {
+			t.Errorf("quality %d: ease factor %v, want %v", tt.quality, s.EaseFactor, tt.want)
+		}
+	}
+}
+
+func TestSM2EaseFactorFloor(t *testing.T) {
+	states := reviewOnDue(sm2Scheduler{}, 0, 0, 0, 5)
+	for i, want := range []float64{1.7, minEaseFactor, minEaseFactor, 1.4} {
+		if math.Abs(states[i].EaseFactor-want) > 1e-9 {
+			t.Errorf("review %d: ease factor %v, want %v", i+1, states[i].EaseFactor, want)
+		}
+	}
+}
+
+func TestSM2Intervals(t *testing.T) {
+	tests := []struct {
+		name      string
+		qualities []int
+		intervals []int
+	}{
+		// 1 day, 6 days, then the previous interval times the ease factor
+		// before the answer: 6 * 2.5 = 15, 15 * 2.5 = 37.5
+		{"good", []int{4, 4, 4, 4}, []int{1, 6, 15, 38}},
+		// The ease factor grows by 0.1 each time: 6 * 2.7 = 16.2, 16 * 2.8 = 44.8
+		{"perfect", []int{5, 5, 5, 5}, []int{1, 6, 16, 45}},
+		// The ease factor drops by 0.14 each time: 6 * 2.22 = 13.32, 13 * 2.08 = 27.04
+		{"hard", []int{3, 3, 3, 3}, []int{1, 6, 13, 27}},
+	}
+	for _, tt := range tests {
+		for i, s := range reviewOnDue(sm2Scheduler{}, tt.qualities...) {
+			if s.IntervalDays != tt.intervals[i] || s.Repetitions != i+1 {
+				t.Errorf("%s, review %d: interval %d, repetitions %d; want %d, %d", tt.name, i+1, s.IntervalDays, s.Repetitions, tt.intervals[i], i+1)
+			}
+			if want := s.LastReviewedAt.AddDate(0, 0, tt.intervals[i]); !s.DueAt.Equal(want) {
+				t.Errorf("%s, review %d: due %v, want %v", tt.name, i+1, s.DueAt, want)
+			}
+		}
+	}
+}
+
+func TestSM2LapseStartsOver(t *testing.T) {
+	states := reviewOnDue(sm2Scheduler{}, 4, 4, 4, 2, 4, 4)
+	lapse := states[3]
+	if lapse.Repetitions != 0 || lapse.IntervalDays != 1 || lapse.Lapses != 1 || math.Abs(lapse.EaseFactor-2.18) > 1e-9 {
+		t.Errorf("after a lapse: %+v, want 0 repetitions, 1 day, 1 lapse and ease 2.18", lapse)
+	}
+	// Relearning goes through 1 and 6 days again, with the lowered ease factor
+	if states[4].IntervalDays != 1 || states[5].IntervalDays != 6 || states[5].Lapses != 1 {
+		t.Errorf("after relearning: %+v, %+v; want 1 then 6 days", states[4], states[5])
+	}
+}
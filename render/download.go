@@ -0,0 +1,93 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	api "github.com/bootdotdev/bootdev/client"
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+type downloadProgressMsg api.DownloadProgress
+
+type downloadDoneMsg struct{}
+
+type downloadModel struct {
+	title    string
+	bar      progress.Model
+	progress api.DownloadProgress
+	cancel   context.CancelFunc
+	canceled bool
+	done     bool
+}
+
+func (m downloadModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m downloadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.canceled = true
+			m.cancel()
+		}
+	case tea.WindowSizeMsg:
+		m.bar.Width = min(msg.Width-4, 60)
+	case downloadProgressMsg:
+		m.progress = api.DownloadProgress(msg)
+	case downloadDoneMsg:
+		m.done = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m downloadModel) View() string {
+	percent := 0.0
+	if m.progress.Total > 0 {
+		percent = float64(m.progress.Done()) / float64(m.progress.Total)
+	}
+	view := fmt.Sprintf("%s\n%s\n%d downloaded, %d failed, %d skipped of %d lessons\n",
+		m.title, m.bar.ViewAs(percent), m.progress.Downloaded, m.progress.Failed, m.progress.Skipped, m.progress.Total)
+	if m.canceled && !m.done {
+		view += gray.Render("Canceling...") + "\n"
+	} else if !m.done {
+		view += gray.Render("ctrl+c cancel") + "\n"
+	}
+	return view
+}
+
+// RenderDownload runs a download of lessons, showing its progress until it
+// ends. Ctrl+C cancels the context of the download, which still returns what
+// it got. Without a terminal, the progress is not shown.
+func RenderDownload(title string, download func(ctx context.Context, onProgress func(api.DownloadProgress)) error) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return download(ctx, nil)
+	}
+
+	m := downloadModel{title: title, bar: progress.New(progress.WithDefaultGradient()), cancel: cancel}
+	m.bar.Width = 60
+	p := tea.NewProgram(m, tea.WithoutSignalHandler())
+	errs := make(chan error, 1)
+	go func() {
+		err := download(ctx, func(current api.DownloadProgress) {
+			p.Send(downloadProgressMsg(current))
+		})
+		p.Send(downloadDoneMsg{})
+		errs <- err
+	}()
+	if _, err := p.Run(); err != nil {
+		cancel()
+		<-errs
+		return err
+	}
+	return <-errs
+}
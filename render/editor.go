@@ -0,0 +1,461 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ============================================================================
+// QUESTION EDITOR - LIST ITEMS
+// ============================================================================
+
+var (
+	labelStyle        = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("241"))
+	focusedLabelStyle = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("170"))
+	errorStyle        = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("9"))
+)
+
+type questionItem struct {
+	question dao.Question
+}
+
+func (i questionItem) Title() string {
+	title, _, _ := strings.Cut(i.question.QuestionText, "\n")
+	if i.question.FlagReason != "" {
+		title = "[" + i.question.FlagReason + "] " + title
+	}
+	if i.question.Archived {
+		title = "[archived] " + title
+	}
+	return title
+}
+
+func (i questionItem) Description() string {
+	return fmt.Sprintf("#%d %s • %s", i.question.ID, i.question.QuestionType, strings.Join(i.question.GetCorrectAnswers(), ", "))
+}
+
+func (i questionItem) FilterValue() string {
+	return i.question.QuestionText + " " + strings.Join(i.question.GetCorrectAnswers(), " ")
+}
+
+// ============================================================================
+// QUESTION EDITOR - FORM FIELDS
+// ============================================================================
+
+// editorField is a text field of the edit form, shown for the question types it applies to
+type editorField struct {
+	name  string
+	label string
+	types []string
+	area  textarea.Model
+}
+
+func (f editorField) appliesTo(questionType string) bool {
+	return len(f.types) == 0 || slices.Contains(f.types, questionType)
+}
+
+func newEditorFields() []editorField {
+	fields := []editorField{
+		{name: "question", label: "Question"},
+		{name: "choices", label: "Choices, one per line", types: []string{"multiple_choice", "multi_select"}},
+		{name: "answer", label: "Answer", types: []string{"multiple_choice", "fill_blank", "free_response"}},
+		{name: "answers", label: "Correct choices, or the items in the correct order, one per line", types: []string{"multi_select", "ordering"}},
+		{name: "accepted", label: "Accepted alternatives, one per line", types: []string{"fill_blank"}},
+		{name: "rubric", label: "Rubric for grading", types: []string{"free_response"}},
+		{name: "explanation", label: "Explanation"},
+	}
+	for i := range fields {
+		ta := textarea.New()
+		ta.ShowLineNumbers = false
+		ta.CharLimit = 0
+		ta.SetHeight(3)
+		fields[i].area = ta
+	}
+	return fields
+}
+
+// lines splits a field into its non-blank lines
+func lines(value string) []string {
+	result := []string{}
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// ============================================================================
+// QUESTION EDITOR - TYPES AND STATE
+// ============================================================================
+
+type editorScreen int
+
+const (
+	browseScreen editorScreen = iota
+	editScreen
+	deleteScreen
+)
+
+var editorKeys = []key.Binding{
+	key.NewBinding(key.WithKeys("enter", "e"), key.WithHelp("enter", "edit")),
+	key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "archive")),
+	key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
+}
+
+type editorModel struct {
+	filter       dao.QuestionFilter
+	list         list.Model
+	screen       editorScreen
+	editing      dao.Question
+	questionType string
+	fields       []editorField
+	// focus is 0 for the question type, then the index of the visible field plus one
+	focus    int
+	err      error
+	quitting bool
+}
+
+// ============================================================================
+// QUESTION EDITOR - BUBBLETEA INTERFACE METHODS
+// ============================================================================
+
+func (m editorModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height)
+		for i := range m.fields {
+			m.fields[i].area.SetWidth(max(20, msg.Width-6))
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		switch m.screen {
+		case editScreen:
+			return m.updateEdit(msg)
+		case deleteScreen:
+			return m.updateDelete(msg)
+		}
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+
+		switch msg.String() {
+		case "q":
+			m.quitting = true
+			return m, tea.Quit
+		case "esc":
+			if !m.list.IsFiltered() {
+				m.quitting = true
+				return m, tea.Quit
+			}
+		case "enter", "e":
+			if item, ok := m.list.SelectedItem().(questionItem); ok {
+				return m.startEdit(item.question), nil
+			}
+			return m, nil
+		case "a":
+			if item, ok := m.list.SelectedItem().(questionItem); ok {
+				return m.toggleArchived(item.question)
+			}
+			return m, nil
+		case "d":
+			if _, ok := m.list.SelectedItem().(questionItem); ok {
+				m.screen = deleteScreen
+			}
+			return m, nil
+		}
+	}
+
+	if m.screen == browseScreen {
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m editorModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	switch m.screen {
+	case editScreen:
+		return m.renderEdit()
+	case deleteScreen:
+		item, _ := m.list.SelectedItem().(questionItem)
+		return "\n" + titleStyle.Render(item.Title()) + "\n\n" +
+			itemStyle.Render("Delete this question and its answer history? (y/n)") + "\n"
+	}
+	return m.list.View()
+}
+
+// ============================================================================
+// QUESTION EDITOR - HELPER METHODS
+// ============================================================================
+
+// reload fetches the questions again after a change, keeping the selection and search
+func (m editorModel) reload(status string) (editorModel, tea.Cmd) {
+	questions, err := dao.GetQuestions(nil, m.filter)
+	if err != nil {
+		return m, m.list.NewStatusMessage(errorStyle.Render(err.Error()))
+	}
+
+	items := make([]list.Item, len(questions))
+	for i, question := range questions {
+		items[i] = questionItem{question}
+	}
+	cmds := []tea.Cmd{m.list.SetItems(items)}
+	if status != "" {
+		cmds = append(cmds, m.list.NewStatusMessage(status))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m editorModel) toggleArchived(question dao.Question) (tea.Model, tea.Cmd) {
+	if err := dao.ArchiveQuestion(nil, question.ID, !question.Archived); err != nil {
+		return m, m.list.NewStatusMessage(errorStyle.Render(err.Error()))
+	}
+	status := "Archived"
+	if question.Archived {
+		status = "Restored"
+	}
+	return m.reload(fmt.Sprintf("%s question #%d", status, question.ID))
+}
+
+func (m editorModel) updateDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.screen = browseScreen
+	item, ok := m.list.SelectedItem().(questionItem)
+	if msg.String() != "y" || !ok {
+		return m, nil
+	}
+	if err := dao.DeleteQuestion(nil, item.question.ID); err != nil {
+		return m, m.list.NewStatusMessage(errorStyle.Render(err.Error()))
+	}
+	return m.reload(fmt.Sprintf("Deleted question #%d", item.question.ID))
+}
+
+// startEdit fills the form with the question. Every field keeps its value when
+// the type changes, so switching back and forth loses nothing.
+func (m editorModel) startEdit(question dao.Question) editorModel {
+	f := question.ToFile()
+	values := map[string]string{
+		"question":    f.Question,
+		"choices":     strings.Join(f.Choices, "\n"),
+		"answer":      f.Answer,
+		"answers":     strings.Join(f.Answers, "\n"),
+		"accepted":    strings.Join(f.AcceptedAnswers, "\n"),
+		"rubric":      f.Rubric,
+		"explanation": f.Explanation,
+	}
+
+	// Prefill the fields of the other types, so a type change starts from the current answers
+	if values["answers"] == "" {
+		values["answers"] = values["answer"]
+	}
+	if values["answer"] == "" && len(f.Answers) > 0 {
+		values["answer"] = f.Answers[0]
+	}
+	if values["choices"] == "" {
+		values["choices"] = values["answers"]
+	}
+
+	for i := range m.fields {
+		m.fields[i].area.SetValue(values[m.fields[i].name])
+		m.fields[i].area.Blur()
+	}
+	m.screen = editScreen
+	m.editing = question
+	m.questionType = question.QuestionType
+	m.err = nil
+	return m.setFocus(1)
+}
+
+// visibleFields returns the indexes of the fields used by the edited question type
+func (m editorModel) visibleFields() []int {
+	visible := []int{}
+	for i, field := range m.fields {
+		if field.appliesTo(m.questionType) {
+			visible = append(visible, i)
+		}
+	}
+	return visible
+}
+
+func (m editorModel) setFocus(focus int) editorModel {
+	visible := m.visibleFields()
+	m.focus = (focus + len(visible) + 1) % (len(visible) + 1)
+	for i, index := range visible {
+		if i+1 == m.focus {
+			m.fields[index].area.Focus()
+		} else {
+			m.fields[index].area.Blur()
+		}
+	}
+	return m
+}
+
+func (m editorModel) changeType(delta int) editorModel {
+	i := slices.Index(dao.QuestionTypes, m.questionType)
+	m.questionType = dao.QuestionTypes[(i+delta+len(dao.QuestionTypes))%len(dao.QuestionTypes)]
+	return m
+}
+
+func (m editorModel) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.screen = browseScreen
+		return m, nil
+	case "ctrl+s":
+		return m.save()
+	case "tab":
+		return m.setFocus(m.focus + 1), nil
+	case "shift+tab":
+		return m.setFocus(m.focus - 1), nil
+	}
+
+	if m.focus == 0 {
+		switch msg.String() {
+		case "left", "h":
+			return m.changeType(-1), nil
+		case "right", "l", " ":
+			return m.changeType(1), nil
+		}
+		return m, nil
+	}
+
+	index := m.visibleFields()[m.focus-1]
+	var cmd tea.Cmd
+	m.fields[index].area, cmd = m.fields[index].area.Update(msg)
+	return m, cmd
+}
+
+// editedQuestion builds the question from the form, keeping only the fields of its type
+func (m editorModel) editedQuestion() dao.QuestionFile {
+	f := m.editing.ToFile()
+	f.Type = m.questionType
+	f.Choices, f.Answer, f.Answers, f.AcceptedAnswers, f.Rubric = nil, "", nil, nil, ""
+
+	for _, index := range m.visibleFields() {
+		value := m.fields[index].area.Value()
+		switch m.fields[index].name {
+		case "question":
+			f.Question = strings.TrimSpace(value)
+		case "choices":
+			f.Choices = lines(value)
+		case "answer":
+			f.Answer = strings.TrimSpace(value)
+		case "answers":
+			f.Answers = lines(value)
+		case "accepted":
+			if accepted := lines(value); len(accepted) > 0 {
+				f.AcceptedAnswers = accepted
+			}
+		case "rubric":
+			f.Rubric = strings.TrimSpace(value)
+		case "explanation":
+			f.Explanation = strings.TrimSpace(value)
+		}
+	}
+	return f
+}
+
+func (m editorModel) save() (tea.Model, tea.Cmd) {
+	if m.err = dao.UpdateQuestion(nil, m.editing.ID, m.editedQuestion()); m.err != nil {
+		return m, nil
+	}
+	m.screen = browseScreen
+	return m.reload(fmt.Sprintf("Saved question #%d", m.editing.ID))
+}
+
+func (m editorModel) renderEdit() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s\n\n", titleStyle.Render(fmt.Sprintf("Editing question #%d", m.editing.ID)))
+	if m.editing.FlagReason != "" {
+		flag := "Flagged as " + m.editing.FlagReason
+		if m.editing.FlagNote != "" {
+			flag += ": " + m.editing.FlagNote
+		}
+		fmt.Fprintf(&b, "%s\n\n", errorStyle.Render(flag+" (saving clears the flag)"))
+	}
+
+	typeStyle := labelStyle
+	if m.focus == 0 {
+		typeStyle = focusedLabelStyle
+	}
+	fmt.Fprintf(&b, "%s\n\n", typeStyle.Render("Type: ‹ "+m.questionType+" ›"))
+
+	for i, index := range m.visibleFields() {
+		field := m.fields[index]
+		style := labelStyle
+		if m.focus == i+1 {
+			style = focusedLabelStyle
+		}
+		fmt.Fprintf(&b, "%s\n%s\n\n", style.Render(field.label), itemStyle.Render(field.area.View()))
+	}
+
+	if m.err != nil {
+		fmt.Fprintf(&b, "%s\n\n", errorStyle.Render("Can't save: "+m.err.Error()))
+	}
+	b.WriteString(helpStyle.Render("tab/shift+tab move • ←/→ change type • ctrl+s save • esc cancel"))
+	return b.String()
+}
+
+// ============================================================================
+// QUESTION EDITOR - CONSTRUCTOR AND PUBLIC INTERFACE
+// ============================================================================
+
+// RenderEditor runs the question bank editor on the questions matching the
+// filter, archived and flagged ones included
+func RenderEditor(filter dao.QuestionFilter) {
+	filter.IncludeArchived = true
+	filter.IncludeFlagged = true
+	questions, err := dao.GetQuestions(nil, filter)
+	if err != nil {
+		fmt.Println("Error getting questions:", err)
+		return
+	}
+	if len(questions) == 0 {
+		fmt.Println("No questions to edit!")
+		return
+	}
+
+	items := make([]list.Item, len(questions))
+	for i, question := range questions {
+		items[i] = questionItem{question}
+	}
+	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Questions of " + filter.CourseUUID
+	l.SetStatusBarItemName("question", "questions")
+	l.DisableQuitKeybindings()
+	l.AdditionalShortHelpKeys = func() []key.Binding { return editorKeys }
+	l.AdditionalFullHelpKeys = func() []key.Binding { return editorKeys }
+
+	m := editorModel{
+		filter: filter,
+		list:   l,
+		fields: newEditorFields(),
+	}
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
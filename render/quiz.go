@@ -1,15 +1,23 @@
 package render
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 
+	api "github.com/bootdotdev/bootdev/client"
 	dao "github.com/bootdotdev/bootdev/db"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/viper"
 )
 
 // ============================================================================
@@ -66,18 +74,50 @@ type quizState int
 
 const (
 	questionState quizState = iota
+	gradingState
 	answerState
+	flaggingState
 	completedState
 )
 
+// gradedMsg carries the result of grading a free_response answer
+type gradedMsg struct {
+	answer string
+	grade  dao.Grade
+	err    error
+}
+
+// courseResult tallies the answers given for one course during a session
+type courseResult struct {
+	answered int
+	correct  int
+}
+
 type model struct {
 	list           list.Model
+	input          textinput.Model
+	textarea       textarea.Model
 	quiz           *dao.Quiz
 	currentQIndex  int
 	selectedAnswer int
+	toggled        map[int]bool
+	order          []string
 	state          quizState
 	quitting       bool
 	lastAnswerOk   bool
+	lastGrade      dao.Grade
+	gradingErr     error
+	recordErr      error
+	flagInput      textinput.Model
+	flagReason     int
+	flagErr        error
+	// stateBeforeFlag is restored when flagging is cancelled
+	stateBeforeFlag quizState
+	reviewSession   bool
+	courses         []string
+	// courseTitles are loaded for the summary of a review session
+	courseTitles map[string]string
+	results      map[string]*courseResult
 }
 
 // ============================================================================
@@ -92,9 +132,29 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.list.SetWidth(msg.Width)
+		m.textarea.SetWidth(max(20, msg.Width-8))
+		return m, nil
+
+	case gradedMsg:
+		if msg.err != nil {
+			m.state = answerState
+			m.gradingErr = msg.err
+			return m, nil
+		}
+		m.recordAnswer(msg.answer, msg.grade)
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.state == flaggingState {
+			return m.updateFlag(msg)
+		}
+		if msg.String() == "ctrl+f" && (m.state == questionState || m.state == answerState) {
+			return m.startFlag()
+		}
+		if m.isTyping() {
+			return m.updateInput(msg)
+		}
+
 		switch keypress := msg.String(); keypress {
 		case "q", "ctrl+c":
 			m.quitting = true
@@ -104,6 +164,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch m.state {
 			case questionState:
 				return m.handleAnswerSelection(), nil
+			case answerState:
+				return m.nextQuestion(), nil
 			default:
 				return m, nil
 			}
@@ -112,6 +174,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.state == answerState {
 				return m.nextQuestion(), nil
 			}
+
+		case "f":
+			if m.state == questionState || m.state == answerState {
+				return m.startFlag()
+			}
+
+		case " ":
+			if m.state == questionState && m.currentQuestion().QuestionType == "multi_select" {
+				return m.toggleChoice(), nil
+			}
+
+		case "shift+up", "K":
+			if m.state == questionState && m.currentQuestion().QuestionType == "ordering" {
+				return m.moveItem(-1), nil
+			}
+
+		case "shift+down", "J":
+			if m.state == questionState && m.currentQuestion().QuestionType == "ordering" {
+				return m.moveItem(1), nil
+			}
 		}
 	}
 
@@ -134,14 +216,42 @@ func (m model) View() string {
 		return m.renderCompletedView()
 	}
 
-	view := "\n" + m.list.View()
+	if m.state == flaggingState {
+		return m.renderFlag()
+	}
+
+	var view string
+	switch m.currentQuestion().QuestionType {
+	case "fill_blank":
+		view = "\n" + m.renderFillBlank()
+	case "free_response":
+		view = "\n" + m.renderFreeResponse()
+	default:
+		view = "\n" + m.list.View()
+	}
+
+	statusStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Margin(0, 0, 1, 4)
+
+	switch questionType := m.currentQuestion().QuestionType; {
+	case m.state == questionState && questionType == "multi_select":
+		view += "\n" + statusStyle.Render("Choose all that apply: space to toggle, enter to submit")
+	case m.state == questionState && questionType == "ordering":
+		view += "\n" + statusStyle.Render("Put the items in order: shift+↑/shift+↓ (or K/J) to move, enter to submit")
+	case m.state == answerState && (questionType == "multi_select" || questionType == "ordering"):
+		view += "\n" + itemStyle.Render(fmt.Sprintf("Score: %.0f%%", m.lastGrade.Score*100)) + "\n"
+	}
 
 	// Add custom status line when in answer state
 	if m.state == answerState {
-		statusStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241")).
-			Margin(0, 0, 1, 4)
-		view += "\n" + statusStyle.Render("Press enter or n for next question")
+		if m.recordErr != nil {
+			view += "\n" + itemStyle.Render(fmt.Sprintf("Couldn't save your answer: %v", m.recordErr)) + "\n"
+		}
+		if lesson := m.currentQuestion().LessonUUID; lesson != "" {
+			view += "\n" + statusStyle.Render("Source lesson: "+viper.GetString("frontend_url")+"/lessons/"+lesson)
+		}
+		view += "\n" + statusStyle.Render("Press enter or n for next question, f to flag it")
 	}
 
 	return view
@@ -151,12 +261,129 @@ func (m model) View() string {
 // QUIZ MODEL - HELPER METHODS
 // ============================================================================
 
+func (m model) currentQuestion() *dao.Question {
+	return &m.quiz.Questions[m.currentQIndex]
+}
+
+// isTyping reports whether keys should go to the text input rather than the quiz controls
+func (m model) isTyping() bool {
+	if m.state != questionState {
+		return false
+	}
+	questionType := m.currentQuestion().QuestionType
+	return questionType == "fill_blank" || questionType == "free_response"
+}
+
+func (m model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	isFreeResponse := m.currentQuestion().QuestionType == "free_response"
+
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "enter":
+		if isFreeResponse {
+			if strings.TrimSpace(m.textarea.Value()) == "" {
+				return m, nil
+			}
+			return m.handleFreeResponse()
+		}
+		if strings.TrimSpace(m.input.Value()) == "" {
+			return m, nil
+		}
+		return m.handleTypedAnswer(), nil
+	}
+
+	var cmd tea.Cmd
+	if isFreeResponse {
+		m.textarea, cmd = m.textarea.Update(msg)
+	} else {
+		m.input, cmd = m.input.Update(msg)
+	}
+	return m, cmd
+}
+
+// submitAnswer grades an answer, stores it and updates the question's schedule
+func (m *model) submitAnswer(answer string) dao.Grade {
+	grade := m.currentQuestion().GradeAnswer(answer)
+	m.recordAnswer(answer, grade)
+	return grade
+}
+
+// recordAnswer stores a graded answer and updates the question's schedule
+func (m *model) recordAnswer(answer string, grade dao.Grade) {
+	currentQ := m.currentQuestion()
+
+	m.recordResult(currentQ.CourseUUID, grade.Correct)
+	m.state = answerState
+	m.lastAnswerOk = grade.Correct
+	m.lastGrade = grade
+	m.recordErr = errors.Join(
+		dao.ReviewQuestion(nil, currentQ.ID, grade.Quality),
+		dao.MarkQuestionAnswered(nil, int(currentQ.ID), answer, grade),
+	)
+}
+
+// choiceItems builds the list items of the current question, with checkboxes
+// for multi_select and in the user's current order for ordering
+func (m model) choiceItems() []list.Item {
+	question := m.currentQuestion()
+	items := []list.Item{}
+	if question.QuestionType == "ordering" {
+		for _, choice := range m.order {
+			items = append(items, item(choice))
+		}
+		return items
+	}
+
+	for i, choice := range question.GetAnswerChoices() {
+		if question.QuestionType == "multi_select" {
+			box := "[ ]"
+			if m.toggled[i] {
+				box = "[x]"
+			}
+			choice = box + " " + choice
+		}
+		items = append(items, item(choice))
+	}
+	return items
+}
+
+func (m model) toggleChoice() model {
+	index := m.list.Index()
+	m.toggled[index] = !m.toggled[index]
+	m.list.SetItems(m.choiceItems())
+	return m
+}
+
+// moveItem moves the selected item of an ordering question up (-1) or down (+1)
+func (m model) moveItem(delta int) model {
+	from := m.list.Index()
+	to := from + delta
+	if to < 0 || to >= len(m.order) {
+		return m
+	}
+
+	m.order = slices.Clone(m.order)
+	m.order[from], m.order[to] = m.order[to], m.order[from]
+	m.list.SetItems(m.choiceItems())
+	m.list.Select(to)
+	return m
+}
+
 func (m model) handleAnswerSelection() model {
+	switch m.currentQuestion().QuestionType {
+	case "multi_select":
+		return m.handleMultiSelect()
+	case "ordering":
+		return m.handleOrdering()
+	}
+
 	m.selectedAnswer = m.list.Index()
 
 	// Update list items with checkmarks
-	currentQ := m.quiz.Questions[m.currentQIndex]
-	choices := currentQ.GetAnswerChoices()
+	choices := m.currentQuestion().GetAnswerChoices()
 	newItems := []list.Item{}
 
 	for i, choice := range choices {
@@ -164,16 +391,10 @@ func (m model) handleAnswerSelection() model {
 
 		if i == m.selectedAnswer {
 			// User's selected answer
-			if currentQ.IsCorrectAnswer(choice) {
+			if m.submitAnswer(choice).Correct {
 				itemText += " ✅"
-				m.state = answerState
-				m.lastAnswerOk = true
-				dao.MarkQuestionAnswered(nil, int(currentQ.ID), choice, true)
 			} else {
 				itemText += " ❌"
-				m.state = answerState
-				m.lastAnswerOk = false
-				dao.MarkQuestionAnswered(nil, int(currentQ.ID), choice, false)
 			}
 		}
 		newItems = append(newItems, item(itemText))
@@ -183,31 +404,242 @@ func (m model) handleAnswerSelection() model {
 	return m
 }
 
+func (m model) handleMultiSelect() model {
+	question := m.currentQuestion()
+	choices := question.GetAnswerChoices()
+
+	selected := []string{}
+	for i, choice := range choices {
+		if m.toggled[i] {
+			selected = append(selected, choice)
+		}
+	}
+	answer, _ := json.Marshal(selected)
+	m.submitAnswer(string(answer))
+
+	// Mark right and wrong picks, and the correct choices that were missed
+	newItems := []list.Item{}
+	for i, choice := range choices {
+		isCorrect := question.IsCorrectChoice(choice)
+		switch {
+		case m.toggled[i] && isCorrect:
+			choice = "[x] " + choice + " ✅"
+		case m.toggled[i]:
+			choice = "[x] " + choice + " ❌"
+		case isCorrect:
+			choice = "[ ] " + choice + " ⬅ missed"
+		default:
+			choice = "[ ] " + choice
+		}
+		newItems = append(newItems, item(choice))
+	}
+
+	m.list.SetItems(newItems)
+	return m
+}
+
+func (m model) handleOrdering() model {
+	answer, _ := json.Marshal(m.order)
+	m.submitAnswer(string(answer))
+
+	// Mark the items in the right place and tell where the others belong
+	correct := m.currentQuestion().GetCorrectAnswers()
+	newItems := []list.Item{}
+	for i, choice := range m.order {
+		if i < len(correct) && correct[i] == choice {
+			choice += " ✅"
+		} else {
+			choice += fmt.Sprintf(" ❌ (goes #%d)", slices.Index(correct, choice)+1)
+		}
+		newItems = append(newItems, item(choice))
+	}
+
+	m.list.SetItems(newItems)
+	return m
+}
+
+// loadOrder starts an ordering question with its items in the shuffled order
+func (m model) loadOrder() model {
+	m.order = nil
+	if question := m.currentQuestion(); question.QuestionType == "ordering" {
+		m.order = question.GetAnswerChoices()
+	}
+	return m
+}
+
+func (m model) handleTypedAnswer() model {
+	m.submitAnswer(m.input.Value())
+	m.input.Blur()
+	return m
+}
+
+// handleFreeResponse sends the answer to the language model for grading in the background
+func (m model) handleFreeResponse() (tea.Model, tea.Cmd) {
+	m.state = gradingState
+	m.gradingErr = nil
+	m.textarea.Blur()
+
+	question := *m.currentQuestion()
+	answer := m.textarea.Value()
+	return m, func() tea.Msg {
+		grade, err := api.GradeFreeResponse(&question, answer)
+		return gradedMsg{answer: answer, grade: grade, err: err}
+	}
+}
+
+func (m model) renderFreeResponse() string {
+	question := m.currentQuestion()
+	view := titleStyle.Render(question.QuestionText) + "\n\n" + itemStyle.Render(m.textarea.View()) + "\n"
+
+	switch m.state {
+	case questionState:
+		view += "\n" + helpStyle.Render("enter submit • alt+enter new line • ctrl+f flag • esc quit")
+	case gradingState:
+		view += "\n" + itemStyle.Render("Grading your answer...") + "\n"
+	case answerState:
+		if m.gradingErr != nil {
+			view += "\n" + itemStyle.Render(fmt.Sprintf("Couldn't grade your answer: %v", m.gradingErr)) + "\n"
+			break
+		}
+		mark := "❌"
+		if m.lastGrade.Correct {
+			mark = "✅"
+		}
+		feedback := fmt.Sprintf("%s Score: %.0f%%\n\n%s\n\nReference answer: %s", mark, m.lastGrade.Score*100, m.lastGrade.Feedback, question.CorrectAnswer)
+		view += "\n" + itemStyle.Render(feedback) + "\n"
+	}
+	return view
+}
+
+func (m model) renderFillBlank() string {
+	question := m.currentQuestion()
+	view := titleStyle.Render(question.QuestionText) + "\n\n" + itemStyle.Render(m.input.View()) + "\n"
+
+	if m.state == answerState {
+		var feedback string
+		switch {
+		case !m.lastGrade.Correct:
+			feedback = fmt.Sprintf("❌ The answer is: %s", question.CorrectAnswer)
+		case m.lastGrade.Quality < dao.AnswerQuality(true):
+			feedback = fmt.Sprintf("✅ Close enough! The answer is: %s", m.lastGrade.Matched)
+		default:
+			feedback = "✅ Correct!"
+		}
+		view += "\n" + itemStyle.Render(feedback) + "\n"
+	} else {
+		view += "\n" + helpStyle.Render("enter submit • ctrl+f flag • esc quit")
+	}
+	return view
+}
+
+// startFlag asks why the current question is flagged, and for an optional note
+func (m model) startFlag() (tea.Model, tea.Cmd) {
+	m.stateBeforeFlag = m.state
+	m.state = flaggingState
+	m.flagReason = 0
+	m.flagErr = nil
+	m.flagInput.Reset()
+	return m, m.flagInput.Focus()
+}
+
+func (m model) updateFlag(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.state = m.stateBeforeFlag
+		m.flagInput.Blur()
+		return m, nil
+	case "tab":
+		m.flagReason = (m.flagReason + 1) % len(dao.FlagReasons)
+		return m, nil
+	case "shift+tab":
+		m.flagReason = (m.flagReason + len(dao.FlagReasons) - 1) % len(dao.FlagReasons)
+		return m, nil
+	case "enter":
+		question := m.currentQuestion()
+		if m.flagErr = dao.FlagQuestion(nil, question.ID, dao.FlagReasons[m.flagReason], m.flagInput.Value()); m.flagErr != nil {
+			return m, nil
+		}
+		// The question leaves the rotation, whether it was answered or not
+		m.flagInput.Blur()
+		return m.nextQuestion(), nil
+	}
+
+	var cmd tea.Cmd
+	m.flagInput, cmd = m.flagInput.Update(msg)
+	return m, cmd
+}
+
+func (m model) renderFlag() string {
+	var reasons []string
+	for i, reason := range dao.FlagReasons {
+		if i == m.flagReason {
+			reasons = append(reasons, selectedItemStyle.Render("> "+reason))
+		} else {
+			reasons = append(reasons, "  "+reason)
+		}
+	}
+
+	view := "\n" + titleStyle.Render("Flag: "+m.currentQuestion().QuestionText) + "\n\n" +
+		itemStyle.Render(strings.Join(reasons, "  ")) + "\n\n" +
+		itemStyle.Render(m.flagInput.View()) + "\n"
+	if m.flagErr != nil {
+		view += "\n" + itemStyle.Render(fmt.Sprintf("Couldn't flag the question: %v", m.flagErr)) + "\n"
+	}
+	return view + "\n" + helpStyle.Render("tab reason • enter flag and skip • esc cancel")
+}
+
+func (m model) recordResult(courseUUID string, isCorrect bool) {
+	result, ok := m.results[courseUUID]
+	if !ok {
+		result = &courseResult{}
+		m.results[courseUUID] = result
+	}
+	result.answered++
+	if isCorrect {
+		result.correct++
+	}
+}
+
 func (m model) nextQuestion() tea.Model {
 	if m.currentQIndex >= len(m.quiz.Questions)-1 {
 		m.state = completedState
+		if m.reviewSession {
+			// Without titles, the summary shows the courses by UUID
+			m.courseTitles, _ = dao.GetCourseTitles(nil, m.courses)
+		}
 		return m
 	}
 
 	m.currentQIndex++
 	m.selectedAnswer = -1
+	m.toggled = map[int]bool{}
 	m.state = questionState
+	m = m.loadOrder()
 
 	// Update list with new question
-	question := m.quiz.Questions[m.currentQIndex]
-	items := []list.Item{}
-	for _, choice := range question.GetAnswerChoices() {
-		items = append(items, item(choice))
-	}
-
-	m.list.SetItems(items)
+	question := m.currentQuestion()
+	m.list.SetItems(m.choiceItems())
 	m.list.Title = question.QuestionText
 	m.list.Select(0) // Reset selection to first item
 
+	m.input.Reset()
+	m.input.Focus()
+	m.textarea.Reset()
+	m.textarea.Focus()
+	m.gradingErr = nil
+	m.recordErr = nil
+
 	return m
 }
 
 func (m model) renderCompletedView() string {
+	if m.reviewSession {
+		return m.renderSessionSummary()
+	}
+
 	baseMsg := "🎉 Quiz completed! Great job!"
 	stats, err := dao.GetQuizStats(nil, m.quiz.CourseUUID)
 	var finalMsg string
@@ -219,6 +651,28 @@ func (m model) renderCompletedView() string {
 	return quitTextStyle.Render(finalMsg)
 }
 
+func (m model) renderSessionSummary() string {
+	var b strings.Builder
+	b.WriteString("🎉 Review session completed! Great job!\n\n")
+
+	answered, correct := 0, 0
+	for _, courseUUID := range m.courses {
+		result, ok := m.results[courseUUID]
+		if !ok {
+			continue
+		}
+		answered += result.answered
+		correct += result.correct
+		title := courseUUID
+		if t, ok := m.courseTitles[courseUUID]; ok {
+			title = t
+		}
+		fmt.Fprintf(&b, " Course: %s\n  Answered: %d\n  Correct: %d\n", title, result.answered, result.correct)
+	}
+	fmt.Fprintf(&b, "\n Total Answers: %d\n Correct Answers: %d\n", answered, correct)
+	return quitTextStyle.Render(b.String())
+}
+
 // ============================================================================
 // QUIZ MODEL - CONSTRUCTOR AND PUBLIC INTERFACE
 // ============================================================================
@@ -227,14 +681,8 @@ func newModel(quiz *dao.Quiz) model {
 	if len(quiz.Questions) == 0 {
 		return model{}
 	}
-	
-	quiz.ShuffleQuestions()
-	q := quiz.Questions[0]
 
-	items := []list.Item{}
-	for _, choice := range q.GetAnswerChoices() {
-		items = append(items, item(choice))
-	}
+	q := quiz.Questions[0]
 
 	const defaultWidth = 20
 
@@ -242,30 +690,76 @@ func newModel(quiz *dao.Quiz) model {
 		quiz:           quiz,
 		currentQIndex:  0,
 		selectedAnswer: -1,
+		toggled:        map[int]bool{},
 		state:          questionState,
 		quitting:       false,
+		results:        map[string]*courseResult{},
+	}
+	for _, question := range quiz.Questions {
+		if !slices.Contains(m.courses, question.CourseUUID) {
+			m.courses = append(m.courses, question.CourseUUID)
+		}
 	}
+	m = m.loadOrder()
 
-	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
+	l := list.New(m.choiceItems(), itemDelegate{}, defaultWidth, listHeight)
 	l.Title = q.QuestionText
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
 	l.Styles.Title = titleStyle
 	l.Styles.PaginationStyle = paginationStyle
 	l.Styles.HelpStyle = helpStyle
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "flag"))}
+	}
 
 	m.list = l
+
+	ti := textinput.New()
+	ti.Placeholder = "Type your answer"
+	ti.Focus()
+	m.input = ti
+
+	fi := textinput.New()
+	fi.Placeholder = "What's wrong with it? (optional)"
+	m.flagInput = fi
+
+	ta := textarea.New()
+	ta.Placeholder = "Explain it in your own words"
+	ta.ShowLineNumbers = false
+	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	ta.Focus()
+	m.textarea = ta
+
 	return m
 }
 
 // RenderQuiz runs a standalone quiz
 func RenderQuiz(quiz *dao.Quiz) {
-	if len(quiz.Questions) == 0 {
+	if quiz == nil || len(quiz.Questions) == 0 {
 		fmt.Println("No questions available!")
 		return
 	}
 
+	quiz.ShuffleQuestions()
+	m := newModel(quiz)
+	if _, err := tea.NewProgram(m).Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+// RenderReview runs a cross-course review session, keeping the question order
+// of the session and summarizing the results per course at the end
+func RenderReview(quiz *dao.Quiz) {
+	if len(quiz.Questions) == 0 {
+		fmt.Println("No questions due for review!")
+		return
+	}
+
+	quiz.ShuffleChoices()
 	m := newModel(quiz)
+	m.reviewSession = true
 	if _, err := tea.NewProgram(m).Run(); err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)